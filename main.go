@@ -4,23 +4,23 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime"
-	"strings"
-	"text/template"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 
-	"go.xrstf.de/prow-aliases-syncer/pkg/git"
 	"go.xrstf.de/prow-aliases-syncer/pkg/github"
-	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
-	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+	"go.xrstf.de/prow-aliases-syncer/pkg/metrics"
+	"go.xrstf.de/prow-aliases-syncer/pkg/scheduler"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
 )
 
 // These variables get set by ldflags during compilation.
@@ -30,6 +30,8 @@ var (
 	BuildDate   string // RFC3339 format ("2006-01-02T15:04:05Z07:00")
 )
 
+const programName = "prow-aliases-syncer"
+
 func printVersion() {
 	fmt.Printf(
 		"Prow Aliases Syncer %s (%s), built with %s on %s\n",
@@ -40,379 +42,166 @@ func printVersion() {
 	)
 }
 
-type options struct {
-	organization       string
-	targetOrganization string
-	branches           []string
-	ignoredUsers       []string
-	bodyFile           string
-	body               *template.Template
-	headerFile         string
-	header             string
-	maxAge             time.Duration
-	dryRun             bool
-	updateDirectly     bool
-	strict             bool
-	keep               bool
-	verbose            bool
-	version            bool
-}
-
-const defaultFileHeader = `
-# This file was automatically generated by prow-aliases-syncer. DO NOT EDIT.
-`
-
-const defaultPRBody = `
-This pull request updates the {{ .Filename }} file based on the GitHub team associations.
-
-**Release Notes:**
-§§§release-note
-NONE
-§§§
-`
-
-// number of commits to retrieve per branch to check for the most recent commit
-const peekDepth = 20
+func main() {
+	args := os.Args[1:]
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			runServe(args[1:])
+			return
+		case "plugin":
+			runPlugin(args[1:])
+			return
+		case "teams":
+			runTeams(args[1:])
+			return
+		case "teams-from-aliases":
+			runTeamsFromAliases(args[1:])
+			return
+		case "plan":
+			runPlan(args[1:])
+			return
+		case "apply":
+			runApply(args[1:])
+			return
+		}
+	}
 
-type templateData struct {
-	Filename   string
-	BaseBranch string
-	HeadBranch string
-	Org        string
-	Repo       string
+	runSync(args)
 }
 
-func main() {
-	body := strings.ReplaceAll(defaultPRBody, "§", "`")
+// runSync performs a single synchronization; this is the default command.
+func runSync(args []string) {
+	opt := defaultOptions()
 
-	opt := options{
-		maxAge: 90 * 24 * time.Hour,
-		header: defaultFileHeader,
-	}
+	var (
+		interval     time.Duration
+		jitter       = 0.1
+		statusListen = ":8080"
+		pushgateway  string
+		pushJob      = programName
+	)
 
-	pflag.StringVarP(&opt.organization, "org", "o", opt.organization, "GitHub organization to load teams from and update repositories in (unless --target-org is given)")
-	pflag.StringVarP(&opt.targetOrganization, "target-org", "t", opt.targetOrganization, "Update repositories in this org based on the teams from --org")
-	pflag.StringVar(&opt.bodyFile, "body", opt.bodyFile, "File with a template for the PR body")
-	pflag.StringVar(&opt.headerFile, "header", opt.headerFile, "File with header for the generated aliases files")
-	pflag.StringSliceVarP(&opt.branches, "branch", "b", opt.branches, "Branch to update (glob expression supported) (can be given multiple times)")
-	pflag.StringSliceVarP(&opt.ignoredUsers, "ignore-user", "i", opt.ignoredUsers, "GitHub usernames which should be ignored when determining the most recent commit on branch (can be given multiple times)")
-	pflag.BoolVar(&opt.dryRun, "dry-run", opt.dryRun, "Do not actually push to GitHub (repositories will still be cloned and locally updated)")
-	pflag.BoolVarP(&opt.strict, "strict", "s", opt.strict, "Compare owners files byte by byte")
-	pflag.BoolVarP(&opt.updateDirectly, "update", "u", opt.updateDirectly, "Do not create pull requests, but directly push into the target branches")
-	pflag.BoolVarP(&opt.keep, "keep", "k", opt.keep, "Keep unknown teams (do not combine with -strict)")
-	pflag.BoolVarP(&opt.verbose, "verbose", "v", opt.verbose, "Enable more verbose output")
-	pflag.BoolVarP(&opt.version, "version", "V", opt.version, "Show version info and exit immediately")
-	pflag.DurationVar(&opt.maxAge, "max-age", opt.maxAge, "Only update branches with commits within this duration")
-	pflag.Parse()
+	fs := pflag.NewFlagSet(programName, pflag.ExitOnError)
+	opt.addFlags(fs)
+	fs.DurationVar(&interval, "interval", interval, "Keep running and synchronize in this interval (0 to synchronize once and exit)")
+	fs.Float64Var(&jitter, "jitter", jitter, "Randomly vary the --interval by this fraction (e.g. 0.1 for ±10%)")
+	fs.StringVar(&statusListen, "status-listen", statusListen, "Address to serve /healthz, /status and /metrics on when using --interval")
+	fs.StringVar(&pushgateway, "pushgateway-url", pushgateway, "Push metrics to this Pushgateway after a single run (ignored when using --interval)")
+	fs.StringVar(&pushJob, "pushgateway-job", pushJob, "Job name to use when pushing metrics")
+	fs.Parse(args)
 
 	if opt.version {
 		printVersion()
 		return
 	}
 
-	// setup logging
-	var log = logrus.New()
-	log.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: time.RFC1123,
-	})
-
-	if opt.verbose {
-		log.SetLevel(logrus.DebugLevel)
-	}
+	log := newLogger(opt.verbose)
+	opt.complete(log)
 
-	// validate CLI flags
-	if opt.organization == "" {
-		log.Fatal("No --org given.")
-	}
+	logger := opt.fieldLogger(log)
 
-	if len(opt.branches) == 0 {
-		log.Fatal("No --branch given.")
-	}
+	ctx := context.Background()
+	client := opt.newClient(ctx, logger)
+	m := metrics.New(client)
+	s := opt.newSyncer(logger, client, m)
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if len(token) == 0 {
-		log.Fatal("No GITHUB_TOKEN environment variable defined.")
+	if interval > 0 {
+		runLoop(logger, &opt, client, m, s, interval, jitter, statusListen)
+		return
 	}
 
-	if len(opt.headerFile) > 0 {
-		content, err := os.ReadFile(opt.headerFile)
-		if err != nil {
-			log.Fatalf("Failed to read --header file: %v", err)
-		}
-
-		opt.header = string(content)
-	}
+	result, err := s.Run(ctx)
+	m.ObserveRun(result, err)
+	opt.saveState(logger)
 
-	if len(opt.bodyFile) > 0 {
-		content, err := os.ReadFile(opt.bodyFile)
-		if err != nil {
-			log.Fatalf("Failed to read --body file: %v", err)
+	if pushgateway != "" {
+		if err := m.Push(pushgateway, pushJob); err != nil {
+			logger.WithError(err).Warn("Failed to push metrics.")
 		}
-
-		body = string(content)
 	}
 
-	tpl, err := template.New("body").Parse(body)
-	if err != nil {
-		log.Fatalf("--body template is not a valid template: %v", err)
-	}
-	opt.body = tpl
-
-	logger := log.WithField("org", opt.organization)
-	if opt.targetOrganization != "" {
-		logger = logger.WithField("target", opt.targetOrganization)
-	} else {
-		opt.targetOrganization = opt.organization
-	}
+	logger = withRunSummary(logger, client, result)
 
-	// setup API client
-	ctx := context.Background()
-
-	client, err := github.NewClient(ctx, logger, token)
 	if err != nil {
-		logger.Fatalf("Failed to create API client: %v", err)
-	}
-
-	if err := work(ctx, client, logger, opt); err != nil {
+		logLimitViolations(logger, err)
 		logger.Fatalf("Failure: %v", err)
 	}
 
 	logger.Info("Synchronization completed.")
 }
 
-func work(ctx context.Context, client *github.Client, log logrus.FieldLogger, opt options) error {
-	// list all teams and their members
-	log.Info("Listing teams…")
-
-	teams, err := client.GetTeams(opt.organization)
-	if err != nil {
-		return err
-	}
-
-	log.Infof("Found %d teams.", len(teams))
-
-	for _, team := range teams {
-		log.WithField("team", team.Slug).WithField("members", team.Members).Debug("Found team.")
-	}
-
-	// list all repos with all branches and the OWNERS_ALIASES file in each of them
-	log.Info("Listing repositories and branches…")
+// runLoop synchronizes periodically until SIGTERM is received. The client
+// and syncer are reused for all runs.
+func runLoop(log logrus.FieldLogger, opt *options, client *github.Client, m *metrics.Metrics, s *syncer.Syncer, interval time.Duration, jitter float64, statusListen string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	sched := scheduler.New(log, interval, jitter, func(ctx context.Context) (*syncer.Result, error) {
+		// do not abort a run in the middle of pushing changes just because
+		// a shutdown was requested
+		result, err := s.Run(context.WithoutCancel(ctx))
+		m.ObserveRun(result, err)
+		opt.saveState(log)
+
+		slog := withRunSummary(log, client, result)
+		if err == nil {
+			slog.Info("Synchronization completed.")
+		} else {
+			logLimitViolations(slog, err)
+		}
 
-	repos, err := client.GetRepositoriesAndBranches(opt.targetOrganization, opt.ignoredUsers, peekDepth)
-	if err != nil {
-		return err
-	}
+		return result, err
+	})
 
-	log.Infof("Found %d repositories.", len(repos))
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	mux.Handle("/", sched.Handler())
 
-	todo, err := createJobs(ctx, log, opt, repos, teams)
-	if err != nil {
-		return fmt.Errorf("failed to determine tasks: %w", err)
-	}
+	go func() {
+		log.WithField("addr", statusListen).Info("Serving status…")
 
-	if len(todo) == 0 {
-		return nil
-	}
+		if err := listenAndServe(ctx, statusListen, mux); err != nil {
+			log.WithError(err).Error("Failed to serve status.")
+		}
+	}()
 
-	if err := processTasks(ctx, client, log, opt, todo); err != nil {
-		return fmt.Errorf("failed to process: %w", err)
-	}
+	sched.Start(ctx)
 
-	return nil
+	log.Info("Shutdown completed.")
 }
 
-func createJobs(ctx context.Context, log logrus.FieldLogger, opt options, repos []github.Repository, teams []github.Team) ([]github.Repository, error) {
-	todo := []github.Repository{}
-
-	for _, r := range repos {
-		rlog := log.WithField("repo", r.Name)
-		branchesToUpdate := []github.Branch{}
-
-		for _, b := range r.Branches {
-			blog := rlog.WithField("branch", b.Name)
-
-			// apply branch filter
-			if !includeBranch(b.Name, opt.branches) {
-				blog.Debug("Ignored.")
-				continue
-			}
-
-			// ignore stale branches
-			if time.Since(b.MostRecentCommit) > opt.maxAge {
-				blog.Debug("No recent activity, ignored.")
-				continue
-			}
-
-			// if the branch has no alias file, ignore it
-			if b.Aliases == "" {
-				blog.Debug("Has no aliases file.")
-				continue
-			}
-
-			equal, newAliases, err := util.Equal(b.Aliases, teams, opt.strict, opt.keep, opt.header)
-			if err != nil {
-				blog.WithError(err).Warn("Invalid aliases file.")
-				continue
-			}
-
-			if !equal {
-				blog.Info("File is not identical.")
-
-				// store the new data so we do not have to generate it again later
-				b.Aliases = newAliases
-
-				branchesToUpdate = append(branchesToUpdate, b)
-			} else {
-				blog.Debug("No changes detected.")
-			}
-		}
+func withRunSummary(log logrus.FieldLogger, client *github.Client, result *syncer.Result) logrus.FieldLogger {
+	stats := client.Stats()
+	log = log.WithFields(logrus.Fields{
+		"api-requests": stats.Requests,
+		"api-cost":     stats.Cost,
+	})
 
-		if len(branchesToUpdate) > 0 {
-			todo = append(todo, github.Repository{
-				ID:       r.ID,
-				Name:     r.Name,
-				Branches: branchesToUpdate,
-			})
-		}
+	if stats.Remaining >= 0 {
+		log = log.WithField("api-remaining", stats.Remaining)
 	}
 
-	return todo, nil
+	return log.WithFields(logrus.Fields{
+		"prs-created":  result.Count(syncer.DecisionPullRequestCreated),
+		"updated":      result.Count(syncer.DecisionUpdated),
+		"pr-fallbacks": result.Fallbacks(),
+		"failed":       result.Count(syncer.DecisionFailed),
+	})
 }
 
-func processTasks(ctx context.Context, client *github.Client, log logrus.FieldLogger, opt options, tasks []github.Repository) error {
-	tmpDir, err := os.MkdirTemp("", "xrstf*")
-	if err != nil {
-		return fmt.Errorf("failed to create temporary directory: %w", err)
-	}
-
-	gitter := git.NewClient(log, opt.verbose)
-
-	for _, task := range tasks {
-		tlog := log.WithField("repo", task.Name)
-		tlog.Info("Processing…")
-
-		cloned := false
-
-		repoURL := fmt.Sprintf("git@github.com:%s/%s.git", opt.targetOrganization, task.Name)
-		repoDir := filepath.Join(tmpDir, task.Name)
-
-		for _, branch := range task.Branches {
-			blog := tlog.WithField("branch", branch.Name)
-			newBranch := fmt.Sprintf("update-%s-owners", branch.Name)
-			newBranch = strings.ReplaceAll(newBranch, "/", "-")
-
-			if !opt.updateDirectly {
-				prNumber, err := client.GetPullRequestForBranch(opt.targetOrganization, task.Name, branch.Name, newBranch)
-				if err != nil {
-					blog.WithError(err).Warn("Failed to check for existing pull request.")
-					continue
-				}
-
-				if prNumber > 0 {
-					blog.WithField("pr", prNumber).Info("Pull request already open.")
-					continue
-				}
-
-			}
-
-			if !cloned {
-				tlog.Debug("Cloning…")
-				if err := gitter.CloneRepository(repoURL, repoDir); err != nil {
-					tlog.WithError(err).Warn("Failed to clone repository.")
-					continue
-				}
-
-				cloned = true
-			}
-
-			// just for safety
-			if err := gitter.ResetRepository(repoDir); err != nil {
-				blog.WithError(err).Warn("Failed to reset working copy.")
-				continue
-			}
-
-			if err := gitter.CheckoutBranch(repoDir, branch.Name); err != nil {
-				blog.WithError(err).Warn("Failed to checkout branch.")
-				continue
-			}
-
-			if !opt.updateDirectly {
-				if err := gitter.CreateBranch(repoDir, newBranch); err != nil {
-					blog.WithError(err).Warn("Failed to create new branch.")
-					continue
-				}
-			}
-
-			filename := filepath.Join(repoDir, prow.OwnersAliasesFilename)
-			if err := os.WriteFile(filename, []byte(branch.Aliases), 0644); err != nil {
-				blog.WithError(err).Warn("Failed to update file.")
-				continue
-			}
-
-			commitMsg := fmt.Sprintf("Synchronize %s file with Github teams", prow.OwnersAliasesFilename)
-			if branch.Name != "master" && branch.Name != "main" {
-				commitMsg = fmt.Sprintf("[%s] %s", branch.Name, commitMsg)
-			}
-
-			if err := gitter.Commit(repoDir, commitMsg); err != nil {
-				blog.WithError(err).Warn("Failed to commit changes.")
-				continue
-			}
-
-			if opt.dryRun {
-				if !opt.updateDirectly {
-					blog = blog.WithField("new-branch", newBranch)
-				}
-
-				blog.Info("Dry run, not pushing branch.")
-				continue
-			}
-
-			if err := gitter.Push(repoDir, "origin", newBranch); err != nil {
-				blog.WithError(err).Warn("Failed to push changes.")
-				continue
-			}
-
-			if opt.updateDirectly {
-				blog.Info("Branch updated.")
-			} else {
-				data := templateData{
-					Filename:   prow.OwnersAliasesFilename,
-					BaseBranch: branch.Name,
-					HeadBranch: newBranch,
-					Org:        opt.targetOrganization,
-					Repo:       task.Name,
-				}
-
-				var buf bytes.Buffer
-				if err := opt.body.Execute(&buf, data); err != nil {
-					blog.WithError(err).Error("Failed to render body template.")
-					continue
-				}
-
-				body := strings.TrimSpace(buf.String())
-
-				prNumber, err := client.CreatePullRequest(task.ID, branch.Name, newBranch, commitMsg, body)
-				if err != nil {
-					blog.WithError(err).Warn("Failed to create pull request.")
-					continue
-				}
-
-				blog.WithField("pr", prNumber).Info("Pull request created.")
-			}
-		}
+// logLimitViolations lists the branches that exceeded the safety limits, if
+// err is a LimitError.
+func logLimitViolations(log logrus.FieldLogger, err error) {
+	var limitErr *syncer.LimitError
+	if !errors.As(err, &limitErr) {
+		return
 	}
 
-	return nil
-}
-
-func includeBranch(branch string, enabled []string) bool {
-	for _, b := range enabled {
-		if matched, _ := filepath.Match(b, branch); matched {
-			return true
-		}
+	for _, v := range limitErr.Violations {
+		log.WithField("violation", v.String()).Error("Safety limit exceeded.")
 	}
 
-	return false
+	log.Error("Nothing was changed; use --ignore-safety-limits if these changes are intended.")
 }
@@ -0,0 +1,574 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/git"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/metrics"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+	"go.xrstf.de/prow-aliases-syncer/pkg/teams"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+)
+
+// options are the flags shared by all commands that synchronize aliases.
+type options struct {
+	organizations       []string
+	targetOrganizations []string
+	aliasSource         string
+	aliasNaming         string
+	conflictPolicy      string
+	branches            []string
+	branchRegexes       []string
+	excludedBranches    []string
+	defaultBranch       bool
+	protectedBranches   bool
+	prowConfigFile      string
+	branchSelector      syncer.BranchSelector
+	aliasesPaths        []string
+	ignoredUsers        []string
+	ignoredCommitters   []string
+	ignoredEmails       []string
+	ignoredMessages     []string
+	ignoredMessageExprs []*regexp.Regexp
+	peekDepth           int
+	activitySignals     []string
+	bodyFile            string
+	body                *template.Template
+	headBranchTemplate  string
+	headBranch          *template.Template
+	commitTemplate      string
+	commitMessage       *template.Template
+	titleTemplate       string
+	title               *template.Template
+	headerFile          string
+	header              string
+	configFile          string
+	config              *config.Config
+	maxAge              time.Duration
+	dryRun              bool
+	updateDirectly      bool
+	strict              bool
+	keep                bool
+	teamsFile           string
+	peribolosConfigs    []string
+	teams               github.TeamLister
+	stateFile           string
+	state               *syncer.State
+	full                bool
+	maxRemovalPercent   int
+	maxWrites           int
+	refuseEmptyAliases  bool
+	ignoreLimits        bool
+	graphqlEndpoint     string
+	restEndpoint        string
+	gitHost             string
+	authorName          string
+	authorEmail         string
+	signOff             bool
+	coAuthors           []string
+	signing             string
+	signingKey          string
+	caBundle            string
+	proxy               string
+	appID               int64
+	appInstallationID   int64
+	appPrivateKeyFile   string
+	verbose             bool
+	version             bool
+
+	clientOpts github.ClientOptions
+}
+
+func defaultOptions() options {
+	return options{
+		maxAge:             90 * 24 * time.Hour,
+		peekDepth:          syncer.DefaultPeekDepth,
+		aliasesPaths:       []string{prow.OwnersAliasesFilename},
+		header:             syncer.DefaultFileHeader,
+		headBranchTemplate: syncer.DefaultHeadBranch,
+		commitTemplate:     syncer.DefaultCommitMessage,
+		titleTemplate:      syncer.DefaultTitle,
+		aliasSource:        string(util.AliasSourceName),
+		aliasNaming:        string(util.AliasNamingPlain),
+		conflictPolicy:     string(util.ConflictError),
+		graphqlEndpoint:    github.DefaultGraphQLEndpoint,
+		restEndpoint:       github.DefaultRESTEndpoint,
+		gitHost:            "github.com",
+	}
+}
+
+// addClientFlags adds the flags required to talk to the GitHub API.
+func (o *options) addClientFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVarP(&o.organizations, "org", "o", o.organizations, "GitHub organization to load teams from and update repositories in (unless --target-org is given) (can be given multiple times)")
+	fs.StringVar(&o.graphqlEndpoint, "graphql-url", o.graphqlEndpoint, "GitHub GraphQL API endpoint (for GitHub Enterprise Server usually https://HOSTNAME/api/graphql)")
+	fs.StringVar(&o.restEndpoint, "rest-url", o.restEndpoint, "GitHub REST API base URL, used for GitHub App authentication and --aliases-path (for GitHub Enterprise Server usually https://HOSTNAME/api/v3)")
+	fs.StringVar(&o.caBundle, "ca-bundle", o.caBundle, "PEM file with additional CA certificates to trust (applies to git only with --app-id, as git uses SSH otherwise)")
+	fs.StringVar(&o.proxy, "proxy", o.proxy, "HTTP proxy URL to use (defaults to the HTTPS_PROXY environment variable) (applies to git only with --app-id, as git uses SSH otherwise)")
+	fs.Int64Var(&o.appID, "app-id", o.appID, "Authenticate as this GitHub App instead of using GITHUB_TOKEN")
+	fs.Int64Var(&o.appInstallationID, "app-installation-id", o.appInstallationID, "Installation ID of the GitHub App")
+	fs.StringVar(&o.appPrivateKeyFile, "app-private-key", o.appPrivateKeyFile, "File with the PEM-encoded private key of the GitHub App")
+	fs.BoolVarP(&o.verbose, "verbose", "v", o.verbose, "Enable more verbose output")
+	fs.BoolVarP(&o.version, "version", "V", o.version, "Show version info and exit immediately")
+}
+
+// addWriteFlags adds the flags that control how changes are pushed.
+func (o *options) addWriteFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.bodyFile, "body", o.bodyFile, "File with a template for the PR body")
+	fs.StringVar(&o.headBranchTemplate, "head-branch", o.headBranchTemplate, "Template for the name of the branch to create pull requests from")
+	fs.StringVar(&o.commitTemplate, "commit-message", o.commitTemplate, "Template for the commit message")
+	fs.StringVar(&o.titleTemplate, "title", o.titleTemplate, "Template for the PR title")
+	fs.BoolVar(&o.dryRun, "dry-run", o.dryRun, "Do not actually push to GitHub (repositories will still be cloned and locally updated)")
+	fs.StringVar(&o.gitHost, "git-host", o.gitHost, "Hostname to clone repositories from and push to")
+	fs.StringVar(&o.authorName, "git-author-name", o.authorName, "Name to use for commits (defaults to the git configuration)")
+	fs.StringVar(&o.authorEmail, "git-author-email", o.authorEmail, "Email to use for commits (defaults to the git configuration)")
+	fs.BoolVar(&o.signOff, "signoff", o.signOff, "Add a Signed-off-by trailer to commits (DCO)")
+	fs.StringSliceVar(&o.coAuthors, "co-author", o.coAuthors, "Add a Co-authored-by trailer, e.g. \"Jane Doe <jane@example.com>\" (can be given multiple times)")
+	fs.StringVar(&o.signing, "sign", o.signing, "Sign commits using \"gpg\" or \"ssh\"")
+	fs.StringVar(&o.signingKey, "signing-key", o.signingKey, "GPG key ID or path to the SSH key to sign commits with")
+}
+
+// addAliasSourceFlag adds the flag that decides how aliases for teams are
+// named; it is shared by all commands that map teams to aliases.
+func (o *options) addAliasSourceFlag(fs *pflag.FlagSet) {
+	fs.StringVar(&o.aliasSource, "alias-source", o.aliasSource, fmt.Sprintf("Name aliases after the team's display name or slug (one of %v)", util.AllAliasSources))
+}
+
+func (o *options) completeAliasSource(log logrus.FieldLogger) {
+	if !slices.Contains(util.AllAliasSources, util.AliasSource(o.aliasSource)) {
+		log.Fatalf("Invalid --alias-source %q, must be one of %v.", o.aliasSource, util.AllAliasSources)
+	}
+}
+
+func (o *options) addFlags(fs *pflag.FlagSet) {
+	o.addClientFlags(fs)
+	o.addWriteFlags(fs)
+
+	fs.StringSliceVarP(&o.targetOrganizations, "target-org", "t", o.targetOrganizations, "Update repositories in this org based on the teams from --org (can be given multiple times)")
+	o.addAliasSourceFlag(fs)
+	fs.StringVar(&o.aliasNaming, "alias-naming", o.aliasNaming, fmt.Sprintf("How to name aliases for teams (one of %v)", util.AllAliasNamings))
+	fs.StringVar(&o.conflictPolicy, "conflict-policy", o.conflictPolicy, fmt.Sprintf("What to do if two organizations have teams with the same alias name (one of %v)", util.AllConflictPolicies))
+	fs.StringVar(&o.headerFile, "header", o.headerFile, "File with header for the generated aliases files")
+	fs.StringVar(&o.configFile, "config", o.configFile, "Configuration file with extra and excluded members per alias and computed aliases")
+	fs.StringSliceVarP(&o.branches, "branch", "b", o.branches, "Branch to update (glob expression supported) (can be given multiple times)")
+	fs.StringSliceVar(&o.branchRegexes, "branch-regex", o.branchRegexes, "Update branches matching this regular expression (can be given multiple times)")
+	fs.StringSliceVar(&o.excludedBranches, "exclude-branch", o.excludedBranches, "Never update this branch, even if selected otherwise (glob expression supported) (can be given multiple times)")
+	fs.BoolVar(&o.defaultBranch, "default-branch", o.defaultBranch, "Update the default branch of each repository")
+	fs.BoolVar(&o.protectedBranches, "protected-branches", o.protectedBranches, "Update all branches with a branch protection rule")
+	fs.StringVar(&o.prowConfigFile, "prow-config", o.prowConfigFile, "Update all branches that are part of a Tide query or the branch-protection section in this Prow config.yaml")
+	fs.StringSliceVar(&o.aliasesPaths, "aliases-path", o.aliasesPaths, "Path of the aliases files to update in each branch (glob expression supported, \"**\" matches any number of directories) (can be given multiple times)")
+	fs.StringSliceVarP(&o.ignoredUsers, "ignore-user", "i", o.ignoredUsers, "GitHub usernames which should be ignored when determining the most recent commit on branch (can be given multiple times)")
+	fs.StringSliceVar(&o.ignoredCommitters, "ignore-committer", o.ignoredCommitters, "GitHub usernames of committers which should be ignored when determining the most recent commit on branch (can be given multiple times)")
+	fs.StringSliceVar(&o.ignoredEmails, "ignore-email", o.ignoredEmails, "Author or committer email addresses which should be ignored when determining the most recent commit on branch (can be given multiple times)")
+	fs.StringSliceVar(&o.ignoredMessages, "ignore-message", o.ignoredMessages, "Regular expression for commit messages which should be ignored when determining the most recent commit on branch (can be given multiple times)")
+	fs.IntVar(&o.peekDepth, "peek-depth", o.peekDepth, "Number of commits per branch to look at when determining the most recent commit (at most 100)")
+	fs.StringSliceVar(&o.activitySignals, "activity-signal", o.activitySignals, fmt.Sprintf("Consider branches without recent commits active if this signal is recent (one of %v) (can be given multiple times)", syncer.AllActivitySignals))
+	fs.BoolVarP(&o.strict, "strict", "s", o.strict, "Compare owners files byte by byte")
+	fs.BoolVarP(&o.updateDirectly, "update", "u", o.updateDirectly, "Do not create pull requests, but directly push into the target branches")
+	fs.BoolVarP(&o.keep, "keep", "k", o.keep, "Keep unknown teams (do not combine with -strict)")
+	fs.StringVar(&o.stateFile, "state-file", o.stateFile, "File to remember branch heads and teams in, to skip unchanged repositories in the next run")
+	fs.BoolVar(&o.full, "full", o.full, "Check all repositories, even if they are unchanged according to the --state-file")
+	fs.IntVar(&o.maxRemovalPercent, "max-removal-percent", o.maxRemovalPercent, "Abort if more than this percentage of the members of any alias would be removed (0 disables the limit)")
+	fs.IntVar(&o.maxWrites, "max-writes", o.maxWrites, "Abort if more than this many branches would be pushed in a single run (0 disables the limit)")
+	fs.BoolVar(&o.refuseEmptyAliases, "refuse-empty-aliases", o.refuseEmptyAliases, "Abort if any alias would end up without any members")
+	fs.BoolVar(&o.ignoreLimits, "ignore-safety-limits", o.ignoreLimits, "Make changes even if they exceed the safety limits")
+	fs.DurationVar(&o.maxAge, "max-age", o.maxAge, "Only update branches with commits within this duration")
+	fs.StringVar(&o.teamsFile, "teams-file", o.teamsFile, "Read teams from this snapshot file (see \"teams export\") instead of from GitHub")
+	fs.StringSliceVar(&o.peribolosConfigs, "peribolos-config", o.peribolosConfigs, "Read teams from this Peribolos org config instead of from GitHub (can be given multiple times)")
+}
+
+func newLogger(verbose bool) *logrus.Logger {
+	var log = logrus.New()
+	log.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: time.RFC1123,
+	})
+
+	if verbose {
+		log.SetLevel(logrus.DebugLevel)
+	}
+
+	return log
+}
+
+// complete validates the flags and loads all referenced files; invalid
+// options terminate the program.
+func (o *options) complete(log logrus.FieldLogger) {
+	o.completeClient(log)
+
+	if o.maxRemovalPercent < 0 || o.maxRemovalPercent > 100 {
+		log.Fatal("--max-removal-percent must be between 0 and 100.")
+	}
+
+	if o.maxWrites < 0 {
+		log.Fatal("--max-writes must not be negative.")
+	}
+
+	if o.peekDepth < 1 || o.peekDepth > 100 {
+		log.Fatal("--peek-depth must be between 1 and 100.")
+	}
+
+	for _, expr := range o.ignoredMessages {
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			log.Fatalf("Invalid --ignore-message %q: %v", expr, err)
+		}
+		o.ignoredMessageExprs = append(o.ignoredMessageExprs, compiled)
+	}
+
+	for _, signal := range o.activitySignals {
+		if !slices.Contains(syncer.AllActivitySignals, syncer.ActivitySignal(signal)) {
+			log.Fatalf("Invalid --activity-signal %q, must be one of %v.", signal, syncer.AllActivitySignals)
+		}
+	}
+
+	if len(o.aliasesPaths) == 0 {
+		log.Fatal("No --aliases-path given.")
+	}
+
+	for _, p := range o.aliasesPaths {
+		if _, err := path.Match(p, ""); err != nil || p == "" || strings.HasPrefix(p, "/") {
+			log.Fatalf("Invalid --aliases-path %q, must be a relative path or glob expression.", p)
+		}
+	}
+
+	o.completeAliasSource(log)
+
+	if !slices.Contains(util.AllAliasNamings, util.AliasNaming(o.aliasNaming)) {
+		log.Fatalf("Invalid --alias-naming %q, must be one of %v.", o.aliasNaming, util.AllAliasNamings)
+	}
+
+	if !slices.Contains(util.AllConflictPolicies, util.ConflictPolicy(o.conflictPolicy)) {
+		log.Fatalf("Invalid --conflict-policy %q, must be one of %v.", o.conflictPolicy, util.AllConflictPolicies)
+	}
+
+	if !slices.Contains(git.AllSigningFormats, git.SigningFormat(o.signing)) {
+		log.Fatalf("Invalid --sign %q, must be one of %v.", o.signing, git.AllSigningFormats[1:])
+	}
+
+	if o.signing == string(git.SigningSSH) && o.signingKey == "" {
+		log.Fatal("--sign=ssh requires a --signing-key.")
+	}
+
+	if len(o.headerFile) > 0 {
+		content, err := os.ReadFile(o.headerFile)
+		if err != nil {
+			log.Fatalf("Failed to read --header file: %v", err)
+		}
+
+		o.header = string(content)
+	}
+
+	body := syncer.DefaultPRBody
+
+	if len(o.bodyFile) > 0 {
+		content, err := os.ReadFile(o.bodyFile)
+		if err != nil {
+			log.Fatalf("Failed to read --body file: %v", err)
+		}
+
+		body = string(content)
+	}
+
+	o.body = parseTemplate(log, "body", body)
+	o.headBranch = parseTemplate(log, "head-branch", o.headBranchTemplate)
+	o.commitMessage = parseTemplate(log, "commit-message", o.commitTemplate)
+	o.title = parseTemplate(log, "title", o.titleTemplate)
+
+	if len(o.targetOrganizations) == 0 {
+		o.targetOrganizations = o.organizations
+	}
+
+	o.completeBranchSelector(log)
+
+	if o.stateFile != "" {
+		state, err := syncer.LoadState(o.stateFile)
+		if err != nil {
+			log.Fatalf("Failed to load --state-file: %v", err)
+		}
+		o.state = state
+	}
+
+	if o.configFile != "" {
+		cfg, err := config.Load(o.configFile)
+		if err != nil {
+			log.Fatalf("Failed to load --config: %v", err)
+		}
+		o.config = cfg
+	}
+
+	if o.teamsFile != "" && len(o.peribolosConfigs) > 0 {
+		log.Fatal("--teams-file and --peribolos-config cannot be combined.")
+	}
+
+	if o.teamsFile != "" {
+		snapshot, err := teams.LoadSnapshot(o.teamsFile)
+		if err != nil {
+			log.Fatalf("Failed to load --teams-file: %v", err)
+		}
+		o.teams = snapshot
+	}
+
+	if len(o.peribolosConfigs) > 0 {
+		config, err := teams.LoadPeribolosConfig(o.peribolosConfigs...)
+		if err != nil {
+			log.Fatalf("Failed to load --peribolos-config: %v", err)
+		}
+		o.teams = config
+	}
+}
+
+// completeBranchSelector combines all flags that select branches.
+func (o *options) completeBranchSelector(log logrus.FieldLogger) {
+	selectors := []syncer.BranchSelector{}
+
+	if len(o.branches) > 0 {
+		selectors = append(selectors, syncer.GlobSelector(o.branches...))
+	}
+
+	if len(o.branchRegexes) > 0 {
+		exprs := []*regexp.Regexp{}
+		for _, expr := range o.branchRegexes {
+			compiled, err := regexp.Compile(expr)
+			if err != nil {
+				log.Fatalf("Invalid --branch-regex %q: %v", expr, err)
+			}
+			exprs = append(exprs, compiled)
+		}
+
+		selectors = append(selectors, syncer.RegexSelector(exprs...))
+	}
+
+	if o.defaultBranch {
+		selectors = append(selectors, syncer.DefaultBranchSelector())
+	}
+
+	if o.protectedBranches {
+		selectors = append(selectors, syncer.ProtectedBranchSelector())
+	}
+
+	if o.prowConfigFile != "" {
+		cfg, err := prow.LoadConfig(o.prowConfigFile)
+		if err != nil {
+			log.Fatalf("Failed to load --prow-config: %v", err)
+		}
+
+		selectors = append(selectors, syncer.ProwConfigSelector(cfg))
+	}
+
+	if len(selectors) == 0 {
+		log.Fatal("No --branch, --branch-regex, --default-branch, --protected-branches or --prow-config given.")
+	}
+
+	for _, pattern := range o.excludedBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Fatalf("Invalid --exclude-branch %q: %v", pattern, err)
+		}
+	}
+
+	o.branchSelector = syncer.AnyOf(selectors...)
+	if len(o.excludedBranches) > 0 {
+		o.branchSelector = syncer.Excluding(o.branchSelector, syncer.GlobSelector(o.excludedBranches...))
+	}
+}
+
+func parseTemplate(log logrus.FieldLogger, flag string, text string) *template.Template {
+	tpl, err := syncer.NewTemplate(flag, text)
+	if err != nil {
+		log.Fatalf("--%s template is not a valid template: %v", flag, err)
+	}
+
+	return tpl
+}
+
+// completeClient validates the flags from addClientFlags.
+func (o *options) completeClient(log logrus.FieldLogger) {
+	if len(o.organizations) == 0 {
+		log.Fatal("No --org given.")
+	}
+
+	o.clientOpts = github.ClientOptions{
+		GraphQLEndpoint: o.graphqlEndpoint,
+		RESTEndpoint:    o.restEndpoint,
+		CABundle:        o.caBundle,
+		Proxy:           o.proxy,
+	}
+
+	if o.appID != 0 {
+		if o.appInstallationID == 0 {
+			log.Fatal("No --app-installation-id given.")
+		}
+
+		if o.appPrivateKeyFile == "" {
+			log.Fatal("No --app-private-key given.")
+		}
+
+		privateKey, err := os.ReadFile(o.appPrivateKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to read --app-private-key file: %v", err)
+		}
+
+		o.clientOpts.App = &github.AppCredentials{
+			AppID:          o.appID,
+			InstallationID: o.appInstallationID,
+			PrivateKey:     privateKey,
+		}
+	} else {
+		o.clientOpts.Token = os.Getenv("GITHUB_TOKEN")
+		if len(o.clientOpts.Token) == 0 {
+			log.Fatal("No GITHUB_TOKEN environment variable defined.")
+		}
+	}
+}
+
+// saveState writes the state file, if one is configured.
+func (o *options) saveState(log logrus.FieldLogger) {
+	if o.state == nil {
+		return
+	}
+
+	if err := o.state.Save(o.stateFile); err != nil {
+		log.WithError(err).Warn("Failed to save state.")
+	}
+}
+
+// fieldLogger returns a logger with the organizations as fields.
+func (o *options) fieldLogger(log *logrus.Logger) logrus.FieldLogger {
+	return log.WithFields(logrus.Fields{
+		"org":    strings.Join(o.organizations, ","),
+		"target": strings.Join(o.targetOrganizations, ","),
+	})
+}
+
+func (o *options) newClient(ctx context.Context, log logrus.FieldLogger) *github.Client {
+	client, err := github.NewClient(ctx, log, o.clientOpts)
+	if err != nil {
+		log.Fatalf("Failed to create API client: %v", err)
+	}
+
+	return client
+}
+
+func (o *options) newSyncer(log logrus.FieldLogger, client *github.Client, m *metrics.Metrics) *syncer.Syncer {
+	gitOpts := git.Options{
+		Verbose:  o.verbose,
+		Host:     o.gitHost,
+		CABundle: o.caBundle,
+		Proxy:    o.proxy,
+
+		AuthorName:  o.authorName,
+		AuthorEmail: o.authorEmail,
+		SignOff:     o.signOff,
+		CoAuthors:   o.coAuthors,
+		Signing:     git.SigningFormat(o.signing),
+		SigningKey:  o.signingKey,
+	}
+
+	// GitHub Apps have no SSH key, so git must use the same short-lived
+	// installation token as the API client
+	if o.appID != 0 {
+		gitOpts.Token = client.Token
+	} else if o.caBundle != "" || o.proxy != "" {
+		log.Warn("git uses SSH unless --app-id is given, so --ca-bundle and --proxy only apply to API requests.")
+	}
+
+	gitClient := git.NewClient(log, gitOpts)
+
+	// fail early instead of after the first repository was modified
+	if err := gitClient.VerifySigning(); err != nil {
+		log.Fatalf("Commit signing does not work: %v", err)
+	}
+
+	clients := syncer.Clients{
+		Teams:        o.teamLister(client),
+		Repositories: client,
+		PullRequests: client,
+		Git:          gitClient,
+		Files:        client,
+		Activity:     client,
+		Protection:   client,
+	}
+
+	opts := o.syncerOptions()
+	m.Instrument(&opts)
+
+	s, err := syncer.New(log, clients, opts)
+	if err != nil {
+		log.Fatalf("Invalid options: %v", err)
+	}
+
+	return s
+}
+
+// teamLister returns the source of the teams: the --teams-file, the
+// --peribolos-config or GitHub.
+func (o *options) teamLister(client *github.Client) github.TeamLister {
+	if o.teams != nil {
+		return o.teams
+	}
+
+	return client
+}
+
+func (o *options) syncerOptions() syncer.Options {
+	activitySignals := []syncer.ActivitySignal{}
+	for _, signal := range o.activitySignals {
+		activitySignals = append(activitySignals, syncer.ActivitySignal(signal))
+	}
+
+	limits := syncer.Limits{
+		MaxRemovalPercent:  o.maxRemovalPercent,
+		MaxWrites:          o.maxWrites,
+		RefuseEmptyAliases: o.refuseEmptyAliases,
+		RefuseEmptyTeams:   true,
+	}
+
+	return syncer.Options{
+		Organizations:       o.organizations,
+		TargetOrganizations: o.targetOrganizations,
+		AliasSource:         util.AliasSource(o.aliasSource),
+		AliasNaming:         util.AliasNaming(o.aliasNaming),
+		ConflictPolicy:      util.ConflictPolicy(o.conflictPolicy),
+		Branches:            o.branches,
+		BranchSelector:      o.branchSelector,
+		AliasesPaths:        o.aliasesPaths,
+		IgnoredUsers:        o.ignoredUsers,
+		IgnoredCommitters:   o.ignoredCommitters,
+		IgnoredEmails:       o.ignoredEmails,
+		IgnoredMessages:     o.ignoredMessageExprs,
+		PeekDepth:           o.peekDepth,
+		ActivitySignals:     activitySignals,
+		MaxAge:              o.maxAge,
+		Config:              o.config,
+		Header:              o.header,
+		Body:                o.body,
+		HeadBranch:          o.headBranch,
+		CommitMessage:       o.commitMessage,
+		Title:               o.title,
+		DryRun:              o.dryRun,
+		UpdateDirectly:      o.updateDirectly,
+		SignedCommits:       o.signing != "",
+		Strict:              o.strict,
+		Keep:                o.keep,
+		Limits:              limits,
+		IgnoreLimits:        o.ignoreLimits,
+		State:               o.state,
+		Full:                o.full,
+	}
+}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package config contains the optional configuration file that refines how
+// aliases are generated from teams.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Version is the version of the configuration file format.
+const Version = 1
+
+type Config struct {
+	Version int `yaml:"version"`
+	// Aliases configures individual aliases, by alias name.
+	Aliases map[string]Alias `yaml:"aliases,omitempty"`
+}
+
+type Alias struct {
+	// Union, Intersection and Difference define a computed alias whose
+	// members are derived from teams and other computed aliases; at most
+	// one of them can be set. Difference removes the members of all other
+	// operands from the first one.
+	Union        []string `yaml:"union,omitempty"`
+	Intersection []string `yaml:"intersection,omitempty"`
+	Difference   []string `yaml:"difference,omitempty"`
+
+	// Extra are members that are always part of the alias, even if they are
+	// not members of the team, e.g. emeritus leads during a handover.
+	Extra []Member `yaml:"extra,omitempty"`
+	// Exclude are members that are never part of the alias, even if they
+	// are members of the team.
+	Exclude []Member `yaml:"exclude,omitempty"`
+}
+
+type Member struct {
+	Login string `yaml:"login"`
+	// Reason is required and added as a comment to the aliases file.
+	Reason string `yaml:"reason"`
+}
+
+// Load reads and validates a configuration file.
+func Load(filename string) (*Config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(f)
+}
+
+func Decode(r io.Reader) (*Config, error) {
+	config := &Config{}
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if c.Version != Version {
+		return fmt.Errorf("unsupported version %d, expected %d", c.Version, Version)
+	}
+
+	for name, alias := range c.Aliases {
+		if name == "" {
+			return errors.New("alias without name")
+		}
+
+		if err := alias.validateExpression(); err != nil {
+			return fmt.Errorf("alias %q: %w", name, err)
+		}
+
+		extra := sets.New[string]()
+
+		for _, member := range alias.Extra {
+			if err := member.validate(); err != nil {
+				return fmt.Errorf("alias %q: %w", name, err)
+			}
+
+			extra.Insert(strings.ToLower(member.Login))
+		}
+
+		for _, member := range alias.Exclude {
+			if err := member.validate(); err != nil {
+				return fmt.Errorf("alias %q: %w", name, err)
+			}
+
+			if extra.Has(strings.ToLower(member.Login)) {
+				return fmt.Errorf("alias %q: %q cannot be both an extra and an excluded member", name, member.Login)
+			}
+		}
+	}
+
+	return c.checkCycles()
+}
+
+func (a Alias) validateExpression() error {
+	expressions := 0
+
+	for _, operands := range [][]string{a.Union, a.Intersection, a.Difference} {
+		if len(operands) == 0 {
+			continue
+		}
+
+		expressions++
+
+		for _, operand := range operands {
+			if operand == "" {
+				return errors.New("empty operand")
+			}
+		}
+	}
+
+	if expressions > 1 {
+		return errors.New("only one of union, intersection and difference can be given")
+	}
+
+	return nil
+}
+
+// IsComputed returns true if the alias is defined by an expression.
+func (a Alias) IsComputed() bool {
+	return len(a.Operands()) > 0
+}
+
+// Operands returns the names of the teams and aliases used in the expression.
+func (a Alias) Operands() []string {
+	switch {
+	case len(a.Union) > 0:
+		return a.Union
+	case len(a.Intersection) > 0:
+		return a.Intersection
+	default:
+		return a.Difference
+	}
+}
+
+// checkCycles ensures that no computed alias depends on itself.
+func (c *Config) checkCycles() error {
+	visited := sets.New[string]()
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		for i, p := range path {
+			if p == name {
+				return fmt.Errorf("cycle in computed aliases: %s", strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+
+		if visited.Has(name) {
+			return nil
+		}
+
+		for _, operand := range c.Aliases[name].Operands() {
+			if err := visit(operand, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		visited.Insert(name)
+
+		return nil
+	}
+
+	for _, name := range sets.List(sets.KeySet(c.Aliases)) {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Dependents returns the names of all computed aliases that directly or
+// indirectly depend on any of the given teams or aliases.
+func (c *Config) Dependents(names []string) []string {
+	affected := sets.New(names...)
+	result := sets.New[string]()
+
+	// the dependency graph is acyclic, so this terminates
+	for changed := true; changed; {
+		changed = false
+
+		for name, alias := range c.Aliases {
+			if result.Has(name) {
+				continue
+			}
+
+			for _, operand := range alias.Operands() {
+				if affected.Has(operand) {
+					affected.Insert(name)
+					result.Insert(name)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return sets.List(result)
+}
+
+func (m Member) validate() error {
+	if m.Login == "" {
+		return errors.New("member without login")
+	}
+
+	if strings.TrimSpace(m.Reason) == "" {
+		return fmt.Errorf("no reason given for %q", m.Login)
+	}
+
+	if strings.Contains(m.Reason, "\n") {
+		return fmt.Errorf("reason for %q must be a single line", m.Login)
+	}
+
+	return nil
+}
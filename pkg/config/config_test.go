@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestDecode(t *testing.T) {
+	testcases := []struct {
+		config string
+		valid  bool
+	}{
+		{
+			config: `
+version: 1
+aliases:
+  sig-a:
+    extra:
+      - login: alice
+        reason: emeritus lead during handover
+    exclude:
+      - login: bob
+        reason: on leave
+`,
+			valid: true,
+		},
+		{
+			config: "version: 2\n",
+			valid:  false,
+		},
+		{
+			config: "version: 1\naliases:\n  sig-a:\n    extra:\n      - login: alice\n",
+			valid:  false,
+		},
+		{
+			config: "version: 1\naliases:\n  sig-a:\n    extra:\n      - login: alice\n        reason: a\n    exclude:\n      - login: Alice\n        reason: b\n",
+			valid:  false,
+		},
+		{
+			config: "version: 1\naliases:\n  sig-a:\n    unknown: true\n",
+			valid:  false,
+		},
+		{
+			config: "version: 1\naliases:\n  a:\n    union: [sig-a]\n    difference: [sig-b]\n",
+			valid:  false,
+		},
+		{
+			config: "version: 1\naliases:\n  a:\n    union: [sig-a, b]\n  b:\n    intersection: [c]\n  c:\n    difference: [a, sig-b]\n",
+			valid:  false,
+		},
+	}
+
+	for i, tt := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.config))
+			if tt.valid && err != nil {
+				t.Fatalf("Expected config to be valid, but got: %v", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Fatal("Expected config to be invalid, but got no error.")
+			}
+		})
+	}
+}
+
+func TestDependents(t *testing.T) {
+	config := &Config{
+		Aliases: map[string]Alias{
+			"a": {Union: []string{"sig-a", "sig-b"}},
+			"b": {Difference: []string{"a", "sig-c"}},
+			"c": {Intersection: []string{"sig-c", "sig-d"}},
+			"d": {Extra: []Member{{Login: "alice", Reason: "test"}}},
+		},
+	}
+
+	if diff := deep.Equal(config.Dependents([]string{"sig-a"}), []string{"a", "b"}); diff != nil {
+		t.Errorf("dependents not equal: %v", diff)
+	}
+
+	if diff := deep.Equal(config.Dependents([]string{"sig-c"}), []string{"b", "c"}); diff != nil {
+		t.Errorf("dependents not equal: %v", diff)
+	}
+}
@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package fake
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/git"
+)
+
+// Files maps filenames to their content.
+type Files map[string]string
+
+// Remote is a repository on the fake git server.
+type Remote struct {
+	// Branches contains the files in each branch.
+	Branches map[string]Files
+	// Heads contains the head commit of each branch.
+	Heads map[string]string
+}
+
+// Commit is a commit that was created in a working copy.
+type Commit struct {
+	Repository string
+	Branch     string
+	Message    string
+}
+
+// Push records a branch being pushed to a remote.
+type Push struct {
+	URL    string
+	Branch string
+	Files  Files
+}
+
+type workingCopy struct {
+	url      string
+	branches map[string]Files
+	heads    map[string]string
+	current  string
+	files    Files
+}
+
+// Git is an in-memory implementation of git.RepositoryWriter. Repositories
+// are identified by their URL, working copies by their directory.
+type Git struct {
+	lock          sync.Mutex
+	workingCopies map[string]*workingCopy
+
+	// Remotes are the repositories on the server, by URL.
+	Remotes map[string]*Remote
+	// Commits contains all commits, in order.
+	Commits []Commit
+	// Pushes contains all pushes, in order.
+	Pushes []Push
+}
+
+var _ git.RepositoryWriter = &Git{}
+
+func NewGit() *Git {
+	return &Git{
+		workingCopies: map[string]*workingCopy{},
+		Remotes:       map[string]*Remote{},
+		Commits:       []Commit{},
+		Pushes:        []Push{},
+	}
+}
+
+// AddRemote registers a repository on the fake git server.
+func (g *Git) AddRemote(org, repo string, branches map[string]Files) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.Remotes[g.RepositoryURL(org, repo)] = &Remote{
+		Branches: branches,
+		Heads:    map[string]string{},
+	}
+}
+
+// SetHead sets the head commit of a branch on the fake git server.
+func (g *Git) SetHead(org, repo, branch, oid string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.Remotes[g.RepositoryURL(org, repo)].Heads[branch] = oid
+}
+
+func (g *Git) RepositoryURL(org, repo string) string {
+	return fmt.Sprintf("fake://%s/%s", org, repo)
+}
+
+func (g *Git) CloneRepository(source, dest string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := g.Remotes[source]; !ok {
+		return fmt.Errorf("repository %q does not exist", source)
+	}
+
+	g.workingCopies[dest] = &workingCopy{
+		url:      source,
+		branches: map[string]Files{},
+		heads:    map[string]string{},
+	}
+
+	return nil
+}
+
+func (g *Git) ResetRepository(repo string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return err
+	}
+
+	if wc.current != "" {
+		wc.files = maps.Clone(wc.branches[wc.current])
+	}
+
+	return nil
+}
+
+func (g *Git) CheckoutBranch(repo, branch string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := wc.branches[branch]; !ok {
+		files, ok := g.Remotes[wc.url].Branches[branch]
+		if !ok {
+			return fmt.Errorf("branch %q does not exist", branch)
+		}
+
+		wc.branches[branch] = maps.Clone(files)
+		wc.heads[branch] = g.Remotes[wc.url].Heads[branch]
+	}
+
+	wc.current = branch
+	wc.files = maps.Clone(wc.branches[branch])
+
+	return nil
+}
+
+func (g *Git) HeadCommit(repo string) (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return "", err
+	}
+
+	return wc.heads[wc.current], nil
+}
+
+func (g *Git) CreateBranch(repo, branch string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return err
+	}
+
+	wc.branches[branch] = maps.Clone(wc.branches[wc.current])
+	wc.heads[branch] = wc.heads[wc.current]
+	wc.current = branch
+
+	return nil
+}
+
+func (g *Git) WriteFile(repo, filename, content string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return err
+	}
+
+	if wc.files == nil {
+		wc.files = Files{}
+	}
+
+	wc.files[filename] = content
+
+	return nil
+}
+
+func (g *Git) Commit(repo, message string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return err
+	}
+
+	if maps.Equal(wc.files, wc.branches[wc.current]) {
+		return fmt.Errorf("nothing to commit on branch %q", wc.current)
+	}
+
+	wc.branches[wc.current] = maps.Clone(wc.files)
+	wc.heads[wc.current] = fmt.Sprintf("commit-%d", len(g.Commits)+1)
+
+	g.Commits = append(g.Commits, Commit{
+		Repository: wc.url,
+		Branch:     wc.current,
+		Message:    message,
+	})
+
+	return nil
+}
+
+func (g *Git) Push(repo, remote, branch string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	wc, err := g.workingCopy(repo)
+	if err != nil {
+		return err
+	}
+
+	files, ok := wc.branches[branch]
+	if !ok {
+		return fmt.Errorf("src refspec %s does not match any", branch)
+	}
+
+	g.Remotes[wc.url].Branches[branch] = maps.Clone(files)
+	g.Remotes[wc.url].Heads[branch] = wc.heads[branch]
+	g.Pushes = append(g.Pushes, Push{
+		URL:    wc.url,
+		Branch: branch,
+		Files:  maps.Clone(files),
+	})
+
+	return nil
+}
+
+func (g *Git) workingCopy(dir string) (*workingCopy, error) {
+	wc, ok := g.workingCopies[dir]
+	if !ok {
+		return nil, fmt.Errorf("%q is not a git repository", dir)
+	}
+
+	return wc, nil
+}
@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package fake
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+// PullRequest is a pull request known to the fake GitHub.
+type PullRequest struct {
+	Org    string
+	Repo   string
+	Number int
+	Base   string
+	Head   string
+	Title  string
+	Body   string
+}
+
+// GitHub is an in-memory implementation of the GitHub interfaces.
+type GitHub struct {
+	lock sync.Mutex
+
+	// Teams are the teams per organization.
+	Teams map[string][]github.Team
+	// Repositories are the repositories per organization.
+	Repositories map[string][]github.Repository
+	// PullRequests contains all open pull requests, including the ones
+	// created through this fake.
+	PullRequests []PullRequest
+	// Comments are the bodies of all created comments, by subject ID.
+	Comments map[string][]string
+	// MergedPullRequests and LatestTags are the activity signals, by
+	// "org/repo/branch".
+	MergedPullRequests map[string]time.Time
+	LatestTags         map[string]time.Time
+	// ProtectedBranches maps "org/repo/branch" to the reason why direct
+	// pushes are not allowed.
+	ProtectedBranches map[string]string
+	// Members are the logins of the organization members, by organization.
+	Members map[string][]string
+}
+
+var (
+	_ github.TeamLister                = &GitHub{}
+	_ github.RepositoryLister          = &GitHub{}
+	_ github.ActivityClient            = &GitHub{}
+	_ github.AliasesFileLister         = &GitHub{}
+	_ github.PullRequestClient         = &GitHub{}
+	_ github.BranchProtectionChecker   = &GitHub{}
+	_ github.Commenter                 = &GitHub{}
+	_ github.OrganizationMemberChecker = &GitHub{}
+)
+
+func NewGitHub() *GitHub {
+	return &GitHub{
+		Teams:        map[string][]github.Team{},
+		Repositories: map[string][]github.Repository{},
+		PullRequests: []PullRequest{},
+		Comments:     map[string][]string{},
+
+		MergedPullRequests: map[string]time.Time{},
+		LatestTags:         map[string]time.Time{},
+		ProtectedBranches:  map[string]string{},
+		Members:            map[string][]string{},
+	}
+}
+
+func (g *GitHub) GetTeams(org string) ([]github.Team, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	teams, ok := g.Teams[org]
+	if !ok {
+		return nil, fmt.Errorf("organization %q does not exist", org)
+	}
+
+	// return deep copies, as callers may modify the member lists
+	result := []github.Team{}
+	for _, team := range teams {
+		team.Members = append([]string{}, team.Members...)
+		team.Roles = maps.Clone(team.Roles)
+		result = append(result, team)
+	}
+
+	return result, nil
+}
+
+func (g *GitHub) GetRepositoriesAndBranches(org string, _ github.ActivityOptions) ([]github.Repository, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	repos, ok := g.Repositories[org]
+	if !ok {
+		return nil, fmt.Errorf("organization %q does not exist", org)
+	}
+
+	result := []github.Repository{}
+	for _, repo := range repos {
+		repo.Branches = listBranches(repo.Branches)
+		result = append(result, repo)
+	}
+
+	return result, nil
+}
+
+func (g *GitHub) GetRepository(org, repo string, _ github.ActivityOptions) (*github.Repository, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for _, r := range g.Repositories[org] {
+		if r.Name == repo {
+			r.Branches = listBranches(r.Branches)
+			return &r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// listBranches copies the branches like GitHub would list them, i.e. only
+// with the aliases file in the root directory.
+func listBranches(branches []github.Branch) []github.Branch {
+	result := []github.Branch{}
+	for _, b := range branches {
+		b.Files = nil
+		result = append(result, b)
+	}
+
+	return result
+}
+
+// GetAliasesFiles returns the matching files of the branch with the given
+// head commit. The Files of a branch (plus its root Aliases) are the files
+// in the branch.
+func (g *GitHub) GetAliasesFiles(org, repo, oid string, patterns []string) (map[string]string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for _, r := range g.Repositories[org] {
+		if r.Name != repo {
+			continue
+		}
+
+		for _, b := range r.Branches {
+			if b.HeadOID != oid {
+				continue
+			}
+
+			files := maps.Clone(b.Files)
+			if files == nil {
+				files = map[string]string{}
+			}
+
+			if b.Aliases != "" {
+				files[prow.OwnersAliasesFilename] = b.Aliases
+			}
+
+			maps.DeleteFunc(files, func(path string, _ string) bool {
+				return !slices.ContainsFunc(patterns, func(pattern string) bool {
+					return prow.MatchPath(pattern, path)
+				})
+			})
+
+			return files, nil
+		}
+	}
+
+	return nil, fmt.Errorf("commit %q does not exist in %s/%s", oid, org, repo)
+}
+
+func (g *GitHub) GetBranchHeads(org string) ([]github.Repository, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	repos, ok := g.Repositories[org]
+	if !ok {
+		return nil, fmt.Errorf("organization %q does not exist", org)
+	}
+
+	result := []github.Repository{}
+	for _, repo := range repos {
+		branches := []github.Branch{}
+		for _, b := range repo.Branches {
+			branches = append(branches, github.Branch{
+				Name:    b.Name,
+				HeadOID: b.HeadOID,
+			})
+		}
+
+		repo.Branches = branches
+		result = append(result, repo)
+	}
+
+	return result, nil
+}
+
+func (g *GitHub) GetPullRequestForBranch(org, repo, baseRef, headRef string) (int, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for _, pr := range g.PullRequests {
+		if pr.Org == org && pr.Repo == repo && pr.Base == baseRef && pr.Head == headRef {
+			return pr.Number, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (g *GitHub) CreatePullRequest(repoID githubv4.ID, baseRef, headRef, title, body string) (int, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for org, repos := range g.Repositories {
+		for _, repo := range repos {
+			if repo.ID != repoID {
+				continue
+			}
+
+			pr := PullRequest{
+				Org:    org,
+				Repo:   repo.Name,
+				Number: len(g.PullRequests) + 1,
+				Base:   baseRef,
+				Head:   headRef,
+				Title:  title,
+				Body:   body,
+			}
+
+			g.PullRequests = append(g.PullRequests, pr)
+
+			return pr.Number, nil
+		}
+	}
+
+	return 0, fmt.Errorf("repository %v does not exist", repoID)
+}
+
+func (g *GitHub) GetPullRequestBaseBranch(org, repo string, number int) (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for _, pr := range g.PullRequests {
+		if pr.Org == org && pr.Repo == repo && pr.Number == number {
+			return pr.Base, nil
+		}
+	}
+
+	return "", fmt.Errorf("pull request %s/%s#%d does not exist", org, repo, number)
+}
+
+func (g *GitHub) CreateComment(subjectID githubv4.ID, body string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	id := fmt.Sprintf("%v", subjectID)
+	g.Comments[id] = append(g.Comments[id], body)
+
+	return nil
+}
+
+func (g *GitHub) GetLastMergedPullRequest(org, repo, branch string) (time.Time, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.MergedPullRequests[fmt.Sprintf("%s/%s/%s", org, repo, branch)], nil
+}
+
+func (g *GitHub) GetLatestTag(org, repo, branch string, since time.Time) (time.Time, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	latest := g.LatestTags[fmt.Sprintf("%s/%s/%s", org, repo, branch)]
+	if latest.Before(since) {
+		return time.Time{}, nil
+	}
+
+	return latest, nil
+}
+
+func (g *GitHub) CanPushDirectly(org, repo, branch string, signed bool) (bool, string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if reason, protected := g.ProtectedBranches[fmt.Sprintf("%s/%s/%s", org, repo, branch)]; protected {
+		return false, reason, nil
+	}
+
+	return true, "", nil
+}
+
+func (g *GitHub) IsOrganizationMember(org, login string) (bool, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return slices.Contains(g.Members[org], login), nil
+}
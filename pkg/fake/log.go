@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package fake
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Logger returns a logger that discards everything but panics, for use in
+// tests.
+func Logger() logrus.FieldLogger {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	return log
+}
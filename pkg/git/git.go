@@ -4,27 +4,106 @@
 package git
 
 import (
+	"encoding/base64"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
+// RepositoryWriter clones repositories and pushes changes to them.
+type RepositoryWriter interface {
+	RepositoryURL(org, repo string) string
+	CloneRepository(source, dest string) error
+	ResetRepository(repo string) error
+	CheckoutBranch(repo, branch string) error
+	HeadCommit(repo string) (string, error)
+	CreateBranch(repo, branch string) error
+	WriteFile(repo, filename, content string) error
+	Commit(repo, message string) error
+	Push(repo, remote, branch string) error
+}
+
+var _ RepositoryWriter = &Client{}
+
 type Client struct {
-	log     logrus.FieldLogger
-	verbose bool
+	log  logrus.FieldLogger
+	opts Options
+}
+
+type Options struct {
+	Verbose bool
+
+	// Host is the git server to clone repositories from, e.g. "github.com".
+	Host string
+
+	// Token, if set, is called before each remote operation and the returned
+	// token is used to authenticate via HTTPS. Without a token, repositories
+	// are cloned via SSH.
+	Token func() (string, error)
+
+	// CABundle is an optional path to a PEM file containing additional CA
+	// certificates to trust when talking to HTTPS remotes.
+	CABundle string
+
+	// Proxy is an optional HTTP proxy used for HTTPS remotes.
+	Proxy string
+
+	// AuthorName and AuthorEmail override the git identity used for commits.
+	AuthorName  string
+	AuthorEmail string
+
+	// SignOff adds a Signed-off-by trailer to each commit (DCO).
+	SignOff bool
+
+	// CoAuthors are added as Co-authored-by trailers to each commit, e.g.
+	// "Jane Doe <jane@example.com>".
+	CoAuthors []string
+
+	// Signing enables signing commits using GPG or SSH; SigningKey is the GPG
+	// key ID or the path to the SSH key. For GPG, the key can be omitted to
+	// use the default key for the committer's email.
+	Signing    SigningFormat
+	SigningKey string
 }
 
-func NewClient(log logrus.FieldLogger, verbose bool) *Client {
+type SigningFormat string
+
+const (
+	SigningNone SigningFormat = ""
+	SigningGPG  SigningFormat = "gpg"
+	SigningSSH  SigningFormat = "ssh"
+)
+
+var AllSigningFormats = []SigningFormat{SigningNone, SigningGPG, SigningSSH}
+
+func NewClient(log logrus.FieldLogger, opts Options) *Client {
 	return &Client{
-		log:     log,
-		verbose: verbose,
+		log:  log,
+		opts: opts,
 	}
 }
 
+// RepositoryURL returns the clone URL for a repository; this is a HTTPS
+// URL if a token is configured and a SSH URL otherwise.
+func (c *Client) RepositoryURL(org, repo string) string {
+	if c.opts.Token != nil {
+		return fmt.Sprintf("https://%s/%s/%s.git", c.opts.Host, org, repo)
+	}
+
+	return fmt.Sprintf("git@%s:%s/%s.git", c.opts.Host, org, repo)
+}
+
 func (c *Client) CloneRepository(source, dest string) error {
-	return c.run("", true, "git", "clone", "--quiet", source, dest)
+	env, err := c.authEnv()
+	if err != nil {
+		return err
+	}
+
+	return c.runWithEnv("", true, env, "git", c.withConfig("clone", "--quiet", source, dest)...)
 }
 
 func (c *Client) ResetRepository(repo string) error {
@@ -43,30 +122,177 @@ func (c *Client) CheckoutBranch(repo, branch string) error {
 	return c.run(repo, true, "git", "checkout", "--quiet", branch)
 }
 
+// HeadCommit returns the commit hash of the current HEAD.
+func (c *Client) HeadCommit(repo string) (string, error) {
+	c.log.Debugf("$ git rev-parse HEAD")
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repo
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 func (c *Client) CreateBranch(repo, branch string) error {
 	return c.run(repo, true, "git", "checkout", "--quiet", "-B", branch)
 }
 
+func (c *Client) WriteFile(repo, filename, content string) error {
+	return os.WriteFile(filepath.Join(repo, filename), []byte(content), 0644)
+}
+
 func (c *Client) Commit(repo, message string) error {
-	return c.run(repo, true, "git", "commit", "--quiet", "--all", "--message", message)
+	return c.run(repo, true, "git", c.commitArgs("--all", "--message", c.withTrailers(message))...)
+}
+
+// VerifySigning creates a signed commit in a temporary repository to ensure
+// that the identity and signing configuration work, before any real
+// repository is modified.
+func (c *Client) VerifySigning() error {
+	if c.opts.Signing == SigningNone {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "verify-signing*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := c.run(dir, true, "git", "init", "--quiet"); err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+
+	if err := c.run(dir, true, "git", c.commitArgs("--allow-empty", "--message", "test")...); err != nil {
+		return fmt.Errorf("failed to create signed commit: %w", err)
+	}
+
+	return nil
+}
+
+// commitArgs returns the arguments for "git commit", including the
+// configuration for the identity and signing.
+func (c *Client) commitArgs(args ...string) []string {
+	config := []string{}
+
+	if c.opts.AuthorName != "" {
+		config = append(config, "-c", fmt.Sprintf("user.name=%s", c.opts.AuthorName))
+	}
+
+	if c.opts.AuthorEmail != "" {
+		config = append(config, "-c", fmt.Sprintf("user.email=%s", c.opts.AuthorEmail))
+	}
+
+	switch c.opts.Signing {
+	case SigningGPG:
+		config = append(config, "-c", "gpg.format=openpgp")
+	case SigningSSH:
+		config = append(config, "-c", "gpg.format=ssh")
+	}
+
+	if c.opts.Signing != SigningNone && c.opts.SigningKey != "" {
+		config = append(config, "-c", fmt.Sprintf("user.signingkey=%s", c.opts.SigningKey))
+	}
+
+	config = append(config, "commit", "--quiet")
+
+	if c.opts.SignOff {
+		config = append(config, "--signoff")
+	}
+
+	if c.opts.Signing != SigningNone {
+		config = append(config, "--gpg-sign")
+	}
+
+	return append(config, args...)
+}
+
+// withTrailers appends the Co-authored-by trailers to a commit message;
+// git adds the Signed-off-by trailer to the same block.
+func (c *Client) withTrailers(message string) string {
+	if len(c.opts.CoAuthors) == 0 {
+		return message
+	}
+
+	trailers := []string{}
+	for _, coAuthor := range c.opts.CoAuthors {
+		trailers = append(trailers, fmt.Sprintf("Co-authored-by: %s", coAuthor))
+	}
+
+	return fmt.Sprintf("%s\n\n%s", strings.TrimSpace(message), strings.Join(trailers, "\n"))
 }
 
 func (c *Client) Push(repo, remote, branch string) error {
 	// do not show stderr so we hide github's remote response text
-	return c.run(repo, false, "git", "push", "--quiet", remote, branch)
+	env, err := c.authEnv()
+	if err != nil {
+		return err
+	}
+
+	return c.runWithEnv(repo, false, env, "git", c.withConfig("push", "--quiet", remote, branch)...)
+}
+
+// authEnv returns environment variables that make git send the current
+// token as an HTTP header. Using the environment instead of "-c" flags keeps
+// the token out of the process list and the debug log.
+func (c *Client) authEnv() ([]string, error) {
+	if c.opts.Token == nil {
+		return nil, nil
+	}
+
+	token, err := c.opts.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+	}, nil
+}
+
+// withConfig prepends the git arguments with per-command configuration
+// flags for the connection to the remote.
+func (c *Client) withConfig(args ...string) []string {
+	config := []string{}
+
+	if c.opts.CABundle != "" {
+		config = append(config, "-c", fmt.Sprintf("http.sslCAInfo=%s", c.opts.CABundle))
+	}
+
+	if c.opts.Proxy != "" {
+		config = append(config, "-c", fmt.Sprintf("http.proxy=%s", c.opts.Proxy))
+	}
+
+	return append(config, args...)
 }
 
 func (c *Client) run(directory string, showErr bool, command string, args ...string) error {
+	return c.runWithEnv(directory, showErr, nil, command, args...)
+}
+
+func (c *Client) runWithEnv(directory string, showErr bool, env []string, command string, args ...string) error {
 	c.log.Debugf("$ %s %s", command, strings.Join(args, " "))
 
 	cmd := exec.Command(command, args...)
 	cmd.Dir = directory
 
-	if c.verbose {
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	if c.opts.Verbose {
 		cmd.Stdout = os.Stdout
 	}
 
-	if c.verbose || showErr {
+	if c.opts.Verbose || showErr {
 		cmd.Stderr = os.Stderr
 	}
 
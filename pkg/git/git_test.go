@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() logrus.FieldLogger {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	return log
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+
+	return string(output)
+}
+
+func TestCommit(t *testing.T) {
+	for _, binary := range []string{"git", "ssh-keygen"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			t.Skipf("%s is not available", binary)
+		}
+	}
+
+	tmpDir := t.TempDir()
+
+	key := filepath.Join(tmpDir, "key")
+	if err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).Run(); err != nil {
+		t.Fatalf("Failed to create SSH key: %v", err)
+	}
+
+	repo := filepath.Join(tmpDir, "repo")
+	if err := os.Mkdir(repo, 0755); err != nil {
+		t.Fatalf("Failed to create repository directory: %v", err)
+	}
+
+	gitOutput(t, repo, "init", "--quiet")
+
+	client := NewClient(testLogger(), Options{
+		AuthorName:  "Sync Bot",
+		AuthorEmail: "bot@example.com",
+		SignOff:     true,
+		CoAuthors:   []string{"Jane Doe <jane@example.com>"},
+		Signing:     SigningSSH,
+		SigningKey:  key,
+	})
+
+	if err := client.VerifySigning(); err != nil {
+		t.Fatalf("Failed to verify signing: %v", err)
+	}
+
+	if err := client.WriteFile(repo, "OWNERS_ALIASES", "aliases: {}\n"); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// "--all" only considers tracked files
+	gitOutput(t, repo, "add", "OWNERS_ALIASES")
+
+	if err := client.Commit(repo, "Update aliases"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	author := gitOutput(t, repo, "log", "-1", "--format=%an <%ae>")
+	if strings.TrimSpace(author) != "Sync Bot <bot@example.com>" {
+		t.Errorf("Unexpected author %q.", author)
+	}
+
+	expectedMessage := "Update aliases\n\nCo-authored-by: Jane Doe <jane@example.com>\nSigned-off-by: Sync Bot <bot@example.com>"
+
+	message := gitOutput(t, repo, "log", "-1", "--format=%B")
+	if strings.TrimSpace(message) != expectedMessage {
+		t.Errorf("Unexpected commit message:\n%s", message)
+	}
+
+	commit := gitOutput(t, repo, "cat-file", "commit", "HEAD")
+	if !strings.Contains(commit, "-----BEGIN SSH SIGNATURE-----") {
+		t.Errorf("Commit is not signed:\n%s", commit)
+	}
+}
+
+func TestVerifySigningFailure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	client := NewClient(testLogger(), Options{
+		AuthorName:  "Sync Bot",
+		AuthorEmail: "bot@example.com",
+		Signing:     SigningSSH,
+		SigningKey:  filepath.Join(t.TempDir(), "does-not-exist"),
+	})
+
+	if err := client.VerifySigning(); err == nil {
+		t.Fatal("Expected an error for a missing signing key, but got none.")
+	}
+}
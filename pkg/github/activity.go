@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/logins"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ActivityOptions control how the most recent commit on a branch is
+// determined. Commits that are ignored (e.g. because they were made by
+// the syncer itself) do not count as activity.
+type ActivityOptions struct {
+	// IgnoredUsers are the logins of commit authors to ignore.
+	IgnoredUsers []string
+	// IgnoredCommitters are the logins of committers to ignore.
+	IgnoredCommitters []string
+	// IgnoredEmails are author or committer email addresses to ignore.
+	IgnoredEmails []string
+	// IgnoredMessages are patterns for commit messages to ignore.
+	IgnoredMessages []*regexp.Regexp
+	// PeekDepth is the number of commits per branch to look at.
+	PeekDepth int
+}
+
+// filtersCommits returns true if any commits are ignored; otherwise the
+// history does not need to be fetched at all.
+func (o ActivityOptions) filtersCommits() bool {
+	return len(o.IgnoredUsers) > 0 || len(o.IgnoredCommitters) > 0 || len(o.IgnoredEmails) > 0 || len(o.IgnoredMessages) > 0
+}
+
+// activityFilter is the prepared form of the ActivityOptions.
+type activityFilter struct {
+	users      sets.Set[string]
+	committers sets.Set[string]
+	emails     sets.Set[string]
+	messages   []*regexp.Regexp
+}
+
+func newActivityFilter(o ActivityOptions) activityFilter {
+	return activityFilter{
+		users:      logins.LowerSet(o.IgnoredUsers),
+		committers: logins.LowerSet(o.IgnoredCommitters),
+		emails:     logins.LowerSet(o.IgnoredEmails),
+		messages:   o.IgnoredMessages,
+	}
+}
+
+func (f activityFilter) ignores(c historyNode) bool {
+	if f.users.Has(strings.ToLower(c.Author.User.Login)) {
+		return true
+	}
+
+	if f.committers.Has(strings.ToLower(c.Committer.User.Login)) {
+		return true
+	}
+
+	if f.emails.Has(strings.ToLower(c.Author.Email)) || f.emails.Has(strings.ToLower(c.Committer.Email)) {
+		return true
+	}
+
+	for _, expr := range f.messages {
+		if expr.MatchString(c.Message) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maxMergedPullRequests is the number of the most recently updated merged
+// pull requests to check in GetLastMergedPullRequest. Pull requests cannot be
+// ordered by their merge date and comments on old pull requests bump them to
+// the top, so looking only at the first one is not enough.
+const maxMergedPullRequests = 20
+
+type lastMergedPullRequestQuery struct {
+	rateLimitQuery
+
+	Repository struct {
+		PullRequests struct {
+			Nodes []struct {
+				MergedAt githubv4.DateTime
+			}
+		} `graphql:"pullRequests(first: $count, states: MERGED, baseRefName: $branch, orderBy: {field: UPDATED_AT, direction: DESC})"`
+	} `graphql:"repository(owner: $owner, name: $repo)"`
+}
+
+// GetLastMergedPullRequest returns when the most recently merged pull request
+// into the branch was merged, or the zero time if there is none. Only the
+// most recently updated pull requests are considered.
+func (c *Client) GetLastMergedPullRequest(org, repo, branch string) (time.Time, error) {
+	variables := map[string]interface{}{
+		"owner":  githubv4.String(org),
+		"repo":   githubv4.String(repo),
+		"branch": githubv4.String(branch),
+		"count":  githubv4.Int(maxMergedPullRequests),
+	}
+
+	var q lastMergedPullRequestQuery
+
+	c.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"branch": branch,
+	}).Debug("GetLastMergedPullRequest()")
+
+	if err := c.query(&q, variables); err != nil {
+		return time.Time{}, err
+	}
+
+	var lastMerged time.Time
+	for _, pr := range q.Repository.PullRequests.Nodes {
+		if pr.MergedAt.After(lastMerged) {
+			lastMerged = pr.MergedAt.Time
+		}
+	}
+
+	return lastMerged, nil
+}
+
+// maxTags is the number of the most recent tags and releases to check in
+// GetLatestTag.
+const maxTags = 10
+
+// tagComparison tells whether the tag is part of the history of a branch.
+type tagComparison struct {
+	Status githubv4.ComparisonStatus
+}
+
+func (c tagComparison) contained() bool {
+	return c.Status == githubv4.ComparisonStatusAhead || c.Status == githubv4.ComparisonStatusIdentical
+}
+
+type latestTagsQuery struct {
+	rateLimitQuery
+
+	Repository struct {
+		// tags can only be sorted by the date of the tagged commit, so
+		// recently created tags for older commits are found via releases
+		Refs struct {
+			Nodes []struct {
+				Name   string
+				Target struct {
+					Commit struct {
+						CommittedDate githubv4.DateTime
+					} `graphql:"... on Commit"`
+					Tag struct {
+						Tagger struct {
+							Date githubv4.GitTimestamp
+						}
+					} `graphql:"... on Tag"`
+				}
+				Compare tagComparison `graphql:"compare(headRef: $branch)"`
+			}
+		} `graphql:"refs(first: $count, refPrefix: $prefix, orderBy: {field: TAG_COMMIT_DATE, direction: DESC})"`
+		Releases struct {
+			Nodes []struct {
+				CreatedAt githubv4.DateTime
+				Tag       struct {
+					Compare tagComparison `graphql:"compare(headRef: $branch)"`
+				}
+			}
+		} `graphql:"releases(first: $count, orderBy: {field: CREATED_AT, direction: DESC})"`
+	} `graphql:"repository(owner: $owner, name: $repo)"`
+}
+
+// GetLatestTag returns when the most recent tag that is part of the history
+// of the given branch was created, or the zero time if none of the most
+// recent tags and releases is. Annotated tags use their tagger date, releases
+// their creation date and lightweight tags the date of the tagged commit.
+// Dates older than since are not considered.
+func (c *Client) GetLatestTag(org, repo, branch string, since time.Time) (time.Time, error) {
+	variables := map[string]interface{}{
+		"owner":  githubv4.String(org),
+		"repo":   githubv4.String(repo),
+		"branch": githubv4.String(branch),
+		"prefix": githubv4.String("refs/tags/"),
+		"count":  githubv4.Int(maxTags),
+	}
+
+	var q latestTagsQuery
+
+	c.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"branch": branch,
+	}).Debug("GetLatestTag()")
+
+	if err := c.query(&q, variables); err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+
+	for _, tag := range q.Repository.Refs.Nodes {
+		// lightweight tags point to commits, annotated tags to tag objects
+		created := tag.Target.Tag.Tagger.Date.Time
+		if created.IsZero() {
+			created = tag.Target.Commit.CommittedDate.Time
+		}
+
+		if tag.Compare.contained() && created.After(latest) {
+			latest = created
+		}
+	}
+
+	for _, release := range q.Repository.Releases.Nodes {
+		if release.Tag.Compare.contained() && release.CreatedAt.After(latest) {
+			latest = release.CreatedAt.Time
+		}
+	}
+
+	if latest.Before(since) {
+		return time.Time{}, nil
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+func TestMostRecentCommit(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	commit := func(age time.Duration, author, authorEmail, committer, message string) historyNode {
+		node := historyNode{
+			CommittedDate: githubv4.DateTime{Time: now.Add(-age)},
+			Message:       message,
+		}
+		node.Author.User.Login = author
+		node.Author.Email = authorEmail
+		node.Committer.User.Login = committer
+		node.Committer.Email = authorEmail
+
+		return node
+	}
+
+	history := []historyNode{
+		commit(1*time.Hour, "bot", "bot@example.com", "web-flow", "Synchronize OWNERS_ALIASES file"),
+		commit(2*time.Hour, "", "unknown@example.com", "", "Merge branch main"),
+		commit(3*time.Hour, "alice", "alice@example.com", "merge-bot", "Fix typo"),
+		commit(4*time.Hour, "bob", "bob@example.com", "bob", "Add feature"),
+	}
+
+	testcases := []struct {
+		name     string
+		activity ActivityOptions
+		expected time.Duration
+	}{
+		{
+			name:     "nothing ignored",
+			activity: ActivityOptions{},
+			expected: 1 * time.Hour,
+		},
+		{
+			name:     "ignored author",
+			activity: ActivityOptions{IgnoredUsers: []string{"Bot"}},
+			expected: 2 * time.Hour,
+		},
+		{
+			name:     "ignored emails",
+			activity: ActivityOptions{IgnoredEmails: []string{"bot@example.com", "unknown@example.com"}},
+			expected: 3 * time.Hour,
+		},
+		{
+			name: "ignored messages and committers",
+			activity: ActivityOptions{
+				IgnoredMessages:   []*regexp.Regexp{regexp.MustCompile(`^(Synchronize|Merge) `)},
+				IgnoredCommitters: []string{"merge-bot"},
+			},
+			expected: 4 * time.Hour,
+		},
+		{
+			name: "all commits ignored",
+			activity: ActivityOptions{
+				IgnoredMessages: []*regexp.Regexp{regexp.MustCompile(`.`)},
+			},
+			// the head commit is used to fail safely
+			expected: 0,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			ref := refNode{Name: "main"}
+			ref.Target.Commit.CommittedDate = githubv4.DateTime{Time: now}
+			ref.Target.Commit.History.Nodes = history
+
+			node := repositoryNode{}
+			node.Refs.Nodes = []refNode{ref}
+
+			repo := convertRepository(node, newActivityFilter(testcase.activity))
+
+			if expected := now.Add(-testcase.expected); !repo.Branches[0].MostRecentCommit.Equal(expected) {
+				t.Fatalf("Expected %v, got %v.", expected, repo.Branches[0].MostRecentCommit)
+			}
+		})
+	}
+}
+
+func TestGetLastMergedPullRequest(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// ordered by update time, not merge time
+		fmt.Fprint(w, `{"data": {"repository": {"pullRequests": {"nodes": [
+			{"mergedAt": "2023-01-10T00:00:00Z"},
+			{"mergedAt": "2023-05-20T00:00:00Z"},
+			{"mergedAt": "2023-03-01T00:00:00Z"}
+		]}}}}`)
+	})
+
+	merged, err := client.GetLastMergedPullRequest("org", "repo", "main")
+	if err != nil {
+		t.Fatalf("Failed to get last merged pull request: %v", err)
+	}
+
+	if expected := time.Date(2023, 5, 20, 0, 0, 0, 0, time.UTC); !merged.Equal(expected) {
+		t.Fatalf("Expected %v, got %v.", expected, merged)
+	}
+}
+
+func TestGetLatestTag(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"repository": {
+			"refs": {"nodes": [
+				{"name": "v1.1.0", "target": {"committedDate": "2023-05-01T00:00:00Z"}, "compare": {"status": "BEHIND"}},
+				{"name": "v1.0.1", "target": {"tagger": {"date": "2023-05-10T00:00:00Z"}}, "compare": {"status": "AHEAD"}},
+				{"name": "v1.0.0", "target": {"committedDate": "2023-02-01T00:00:00Z"}, "compare": {"status": "IDENTICAL"}}
+			]},
+			"releases": {"nodes": [
+				{"createdAt": "2023-06-01T00:00:00Z", "tag": {"compare": {"status": "DIVERGED"}}},
+				{"createdAt": "2023-05-20T00:00:00Z", "tag": {"compare": {"status": "AHEAD"}}}
+			]}
+		}}}`)
+	})
+
+	testcases := []struct {
+		name     string
+		since    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "release created after the latest tag",
+			since:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, 5, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "too old",
+			since: time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			latest, err := client.GetLatestTag("org", "repo", "release-1.0", testcase.since)
+			if err != nil {
+				t.Fatalf("Failed to get latest tag: %v", err)
+			}
+
+			if !latest.Equal(testcase.expected) {
+				t.Fatalf("Expected %v, got %v.", testcase.expected, latest)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const DefaultRESTEndpoint = "https://api.github.com"
+
+// AppCredentials are used to authenticate as a GitHub App installation.
+type AppCredentials struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     []byte
+}
+
+// installation tokens are valid for 1 hour; refresh them a bit earlier so
+// that long-running git operations do not suddenly fail
+const installationTokenLeeway = 5 * time.Minute
+
+type appTokenSource struct {
+	restEndpoint string
+	httpClient   *http.Client
+	creds        AppCredentials
+	key          *rsa.PrivateKey
+}
+
+// NewAppTokenSource returns a token source that mints installation tokens
+// for a GitHub App and caches them until shortly before they expire.
+func NewAppTokenSource(restEndpoint string, httpClient *http.Client, creds AppCredentials) (oauth2.TokenSource, error) {
+	if creds.AppID == 0 {
+		return nil, errors.New("app ID cannot be empty")
+	}
+
+	if creds.InstallationID == 0 {
+		return nil, errors.New("installation ID cannot be empty")
+	}
+
+	key, err := parsePrivateKey(creds.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	if restEndpoint == "" {
+		restEndpoint = DefaultRESTEndpoint
+	}
+
+	src := &appTokenSource{
+		restEndpoint: strings.TrimSuffix(restEndpoint, "/"),
+		httpClient:   httpClient,
+		creds:        creds,
+		key:          key,
+	}
+
+	return oauth2.ReuseTokenSource(nil, src), nil
+}
+
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.signJWT(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWT: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.restEndpoint, s.creds.InstallationID)
+
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to request installation token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var response struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: response.Token,
+		TokenType:   "Bearer",
+		Expiry:      response.ExpiresAt.Add(-installationTokenLeeway),
+	}, nil
+}
+
+func (s *appTokenSource) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// backdate the token to allow for some clock drift, see
+	// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprintf("%d", s.creds.AppID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			t.Fatalf("Invalid JWT: %q", jwt)
+		}
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Fatalf("Invalid JWT signature encoding: %v", err)
+		}
+
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+			t.Errorf("Invalid JWT signature: %v", err)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_test%d","expires_at":%q}`, requests, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	src, err := NewAppTokenSource(server.URL, server.Client(), AppCredentials{
+		AppID:          1,
+		InstallationID: 42,
+		PrivateKey:     keyPEM,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token source: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := src.Token()
+		if err != nil {
+			t.Fatalf("Failed to get token: %v", err)
+		}
+
+		if token.AccessToken != "ghs_test1" {
+			t.Errorf("Expected token ghs_test1, got %q.", token.AccessToken)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected token to be minted once, but got %d requests.", requests)
+	}
+}
@@ -12,51 +12,85 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
-
-	"k8s.io/apimachinery/pkg/util/sets"
 )
 
+// SettingsFilename is the optional file in each branch that allows
+// repository owners to control the synchronization.
+const SettingsFilename = ".prow-aliases-syncer.yaml"
+
+type repositoryNode struct {
+	ID               githubv4.ID
+	Name             string
+	DefaultBranchRef *struct {
+		Name string
+	}
+	Refs struct {
+		Nodes []refNode
+	} `graphql:"refs(first: 100, orderBy: {field: ALPHABETICAL, direction: ASC}, refPrefix: $prefix)"`
+}
+
+type refNode struct {
+	Name string
+	// only visible if the token is allowed to see the rules
+	BranchProtectionRule *struct {
+		ID githubv4.ID
+	}
+	Target struct {
+		Commit commitNode `graphql:"... on Commit"`
+	}
+}
+
+type commitNode struct {
+	OID           string
+	CommittedDate githubv4.DateTime
+
+	// fetch the current state of the OWNERS_ALIASES file
+	File struct {
+		Object struct {
+			Blob struct {
+				Text string
+			} `graphql:"... on Blob"`
+		}
+	} `graphql:"file(path: $filename)"`
+
+	// fetch the optional settings file, which allows repository
+	// owners to control the synchronization
+	Settings struct {
+		Object struct {
+			Blob struct {
+				Text string
+			} `graphql:"... on Blob"`
+		}
+	} `graphql:"settings: file(path: $settingsFilename)"`
+
+	// fetch the most recent history for this branch, so we
+	// can check if the activity was only caused by us updating
+	// the owners file, or if there are other commits in here
+	History struct {
+		Nodes []historyNode
+	} `graphql:"history(first: $peek)"`
+}
+
+type historyNode struct {
+	CommittedDate githubv4.DateTime
+	Message       string
+	Author        commitActor
+	Committer     commitActor
+}
+
+type commitActor struct {
+	Email string
+	User  struct {
+		Login string
+	}
+}
+
 type repositoriesBranchesQuery struct {
+	rateLimitQuery
+
 	Organization struct {
 		Repositories struct {
-			Nodes []struct {
-				ID   githubv4.ID
-				Name string
-				Refs struct {
-					Nodes []struct {
-						Name   string
-						Target struct {
-							Commit struct {
-								OID           string
-								CommittedDate githubv4.DateTime
-
-								// fetch the current state of the OWNERS_ALIASES file
-								File struct {
-									Object struct {
-										Blob struct {
-											Text string
-										} `graphql:"... on Blob"`
-									}
-								} `graphql:"file(path: $filename)"`
-
-								// fetch the most recent history for this branch, so we
-								// can check if the activity was only caused by us updating
-								// the owners file, or if there are other commits in here
-								History struct {
-									Nodes []struct {
-										CommittedDate githubv4.DateTime
-										Author        struct {
-											User struct {
-												Login string
-											}
-										}
-									}
-								} `graphql:"history(first: $peek)"`
-							} `graphql:"... on Commit"`
-						}
-					}
-				} `graphql:"refs(first: 100, orderBy: {field: ALPHABETICAL, direction: ASC}, refPrefix: $prefix)"`
-			}
+			Nodes    []repositoryNode
 			PageInfo struct {
 				EndCursor   githubv4.String
 				HasNextPage bool
@@ -65,28 +99,63 @@ type repositoriesBranchesQuery struct {
 	} `graphql:"organization(login: $login)"`
 }
 
+type repositoryBranchesQuery struct {
+	rateLimitQuery
+
+	Repository *repositoryNode `graphql:"repository(owner: $login, name: $repo)"`
+}
+
 type Repository struct {
-	ID       githubv4.ID
-	Name     string
-	Branches []Branch
+	ID   githubv4.ID
+	Name string
+	// DefaultBranch is the name of the default branch, if known.
+	DefaultBranch string
+	Branches      []Branch
 }
 
 type Branch struct {
 	Name             string
+	HeadOID          string
 	MostRecentCommit time.Time
-	Aliases          string
+	// Aliases is the content of the OWNERS_ALIASES file in the root
+	// directory.
+	Aliases string
+	// Protected is true if a branch protection rule applies to the branch.
+	Protected bool
+	// Settings is the content of the settings file (see SettingsFilename)
+	// in the root directory.
+	Settings string
+	// Files maps the paths of all aliases files in the branch to their
+	// content. It is only set if the files were discovered explicitly,
+	// otherwise the root Aliases file is the only one.
+	Files map[string]string
 }
 
-func (c *Client) GetRepositoriesAndBranches(org string, ignoredUsers []string, peekDepth int) ([]Repository, error) {
+// AliasesFiles returns all known aliases files in the branch by path.
+func (b Branch) AliasesFiles() map[string]string {
+	if b.Files != nil {
+		return b.Files
+	}
+
+	if b.Aliases == "" {
+		return map[string]string{}
+	}
+
+	return map[string]string{
+		prow.OwnersAliasesFilename: b.Aliases,
+	}
+}
+
+func (c *Client) GetRepositoriesAndBranches(org string, activity ActivityOptions) ([]Repository, error) {
 	result := []Repository{}
 	cursor := ""
 
-	// secret optimization: if no users are ignored (this should never happen,
+	// secret optimization: if no commits are ignored (this should never happen,
 	// as you should always ignore the bot who runs this tool), there is no need
 	// to peek into any commits, we can just take the commitDate from the latest
 	// commit
-	if len(ignoredUsers) == 0 {
-		peekDepth = 0
+	if !activity.filtersCommits() {
+		activity.PeekDepth = 0
 	}
 
 	for {
@@ -95,7 +164,7 @@ func (c *Client) GetRepositoriesAndBranches(org string, ignoredUsers []string, p
 			err   error
 		)
 
-		items, cursor, err = c.getRepositoriesAndBranches(org, ignoredUsers, peekDepth, cursor)
+		items, cursor, err = c.getRepositoriesAndBranches(org, activity, cursor)
 		if err != nil {
 			return nil, err
 		}
@@ -114,13 +183,14 @@ func (c *Client) GetRepositoriesAndBranches(org string, ignoredUsers []string, p
 	return result, nil
 }
 
-func (c *Client) getRepositoriesAndBranches(org string, ignoredUsers []string, peekDepth int, cursor string) ([]Repository, string, error) {
+func (c *Client) getRepositoriesAndBranches(org string, activity ActivityOptions, cursor string) ([]Repository, string, error) {
 	variables := map[string]interface{}{
-		"filename": githubv4.String(prow.OwnersAliasesFilename),
-		"login":    githubv4.String(org),
-		"prefix":   githubv4.String("refs/heads/"),
-		"cursor":   (*githubv4.String)(nil),
-		"peek":     githubv4.Int(peekDepth),
+		"filename":         githubv4.String(prow.OwnersAliasesFilename),
+		"settingsFilename": githubv4.String(SettingsFilename),
+		"login":            githubv4.String(org),
+		"prefix":           githubv4.String("refs/heads/"),
+		"cursor":           (*githubv4.String)(nil),
+		"peek":             githubv4.Int(activity.PeekDepth),
 	}
 
 	if cursor != "" {
@@ -134,52 +204,15 @@ func (c *Client) getRepositoriesAndBranches(org string, ignoredUsers []string, p
 		"cursor": string(cursor),
 	}).Debug("getRepositoriesAndBranches()")
 
-	// igore errors because a missing file in a branch would cause an error
-	// and we have no option to introspect the error
-	_ = c.client.Query(c.ctx, &q, variables)
-	// if err != nil {
-	// 	return nil, "", err
-	// }
+	if err := ignoreBranchQueryErrors(c.query(&q, variables)); err != nil {
+		return nil, "", err
+	}
 
 	result := []Repository{}
-	ignored := sets.NewString(ignoredUsers...)
+	filter := newActivityFilter(activity)
 
 	for _, r := range q.Organization.Repositories.Nodes {
-		repo := Repository{
-			ID:       r.ID,
-			Name:     r.Name,
-			Branches: []Branch{},
-		}
-
-		for _, b := range r.Refs.Nodes {
-			// if the following loop finds no commit (e.g. because we ignore all
-			// relevant users), we want to assume that the branch is "alive" and
-			// needs updating, so that we fail safely (i.e. branches do not get lost
-			// because we didn't peek far enough into their history)
-			mostRecentCommit := b.Target.Commit.CommittedDate.Time
-
-			// look through the most recent N commits and find the most recent one,
-			// while ignoring a certain group of users (i.e. do not count the commits
-			// that this tool is producing)
-			for _, c := range b.Target.Commit.History.Nodes {
-				if !ignored.Has(c.Author.User.Login) {
-					mostRecentCommit = c.CommittedDate.Time
-					break
-				}
-			}
-
-			repo.Branches = append(repo.Branches, Branch{
-				Name:             b.Name,
-				MostRecentCommit: mostRecentCommit,
-				Aliases:          b.Target.Commit.File.Object.Blob.Text,
-			})
-		}
-
-		sort.Slice(repo.Branches, func(i, j int) bool {
-			return strings.ToLower(repo.Branches[i].Name) < strings.ToLower(repo.Branches[j].Name)
-		})
-
-		result = append(result, repo)
+		result = append(result, convertRepository(r, filter))
 	}
 
 	newCursor := ""
@@ -189,3 +222,105 @@ func (c *Client) getRepositoriesAndBranches(org string, ignoredUsers []string, p
 
 	return result, newCursor, nil
 }
+
+// GetRepository returns a single repository with all its branches. If the
+// repository does not exist, nil is returned.
+func (c *Client) GetRepository(org, repo string, activity ActivityOptions) (*Repository, error) {
+	if !activity.filtersCommits() {
+		activity.PeekDepth = 0
+	}
+
+	variables := map[string]interface{}{
+		"filename":         githubv4.String(prow.OwnersAliasesFilename),
+		"settingsFilename": githubv4.String(SettingsFilename),
+		"login":            githubv4.String(org),
+		"repo":             githubv4.String(repo),
+		"prefix":           githubv4.String("refs/heads/"),
+		"peek":             githubv4.Int(activity.PeekDepth),
+	}
+
+	var q repositoryBranchesQuery
+
+	c.log.WithFields(logrus.Fields{
+		"org":  org,
+		"repo": repo,
+	}).Debug("GetRepository()")
+
+	if err := ignoreBranchQueryErrors(c.query(&q, variables)); err != nil {
+		return nil, err
+	}
+
+	if q.Repository == nil {
+		return nil, nil
+	}
+
+	result := convertRepository(*q.Repository, newActivityFilter(activity))
+
+	return &result, nil
+}
+
+// ignoreBranchQueryErrors ignores GraphQL errors because a missing file in a
+// branch would cause an error and we have no option to introspect the error;
+// the exceptions are servers that do not support the query at all and
+// requests that failed entirely, as these would otherwise silently lead to
+// partial results.
+func ignoreBranchQueryErrors(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if schemaErr := asSchemaError(err); schemaErr != nil {
+		return schemaErr
+	}
+
+	if isTransportError(err) || isPrimaryRateLimitError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func convertRepository(r repositoryNode, filter activityFilter) Repository {
+	repo := Repository{
+		ID:       r.ID,
+		Name:     r.Name,
+		Branches: []Branch{},
+	}
+
+	if r.DefaultBranchRef != nil {
+		repo.DefaultBranch = r.DefaultBranchRef.Name
+	}
+
+	for _, b := range r.Refs.Nodes {
+		// if the following loop finds no commit (e.g. because we ignore all
+		// relevant users), we want to assume that the branch is "alive" and
+		// needs updating, so that we fail safely (i.e. branches do not get lost
+		// because we didn't peek far enough into their history)
+		mostRecentCommit := b.Target.Commit.CommittedDate.Time
+
+		// look through the most recent N commits and find the most recent one,
+		// while ignoring certain commits (i.e. do not count the commits that
+		// this tool is producing)
+		for _, c := range b.Target.Commit.History.Nodes {
+			if !filter.ignores(c) {
+				mostRecentCommit = c.CommittedDate.Time
+				break
+			}
+		}
+
+		repo.Branches = append(repo.Branches, Branch{
+			Name:             b.Name,
+			HeadOID:          b.Target.Commit.OID,
+			MostRecentCommit: mostRecentCommit,
+			Aliases:          b.Target.Commit.File.Object.Blob.Text,
+			Settings:         b.Target.Commit.Settings.Object.Blob.Text,
+			Protected:        b.BranchProtectionRule != nil,
+		})
+	}
+
+	sort.Slice(repo.Branches, func(i, j int) bool {
+		return strings.ToLower(repo.Branches[i].Name) < strings.ToLower(repo.Branches[j].Name)
+	})
+
+	return repo
+}
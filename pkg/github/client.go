@@ -5,35 +5,187 @@ package github
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 )
 
+const DefaultGraphQLEndpoint = "https://api.github.com/graphql"
+
 type Client struct {
-	ctx    context.Context
-	client *githubv4.Client
-	log    logrus.FieldLogger
+	ctx          context.Context
+	client       *githubv4.Client
+	httpClient   *http.Client
+	restEndpoint string
+	tokenSource  oauth2.TokenSource
+	log          logrus.FieldLogger
+	minRemaining int
+
+	statsLock sync.Mutex
+	stats     Stats
+}
+
+type ClientOptions struct {
+	// Token is a personal access token. Either this or App must be set.
+	Token string
+
+	// App are the credentials to authenticate as a GitHub App installation.
+	App *AppCredentials
+
+	// GraphQLEndpoint is the URL of the GraphQL API. For GitHub Enterprise
+	// Server this is usually "https://HOSTNAME/api/graphql". Defaults to
+	// DefaultGraphQLEndpoint.
+	GraphQLEndpoint string
+
+	// RESTEndpoint is the base URL of the REST API, which is required to
+	// mint GitHub App installation tokens and to list the files in a
+	// repository. For GitHub Enterprise Server this
+	// is usually "https://HOSTNAME/api/v3". Defaults to DefaultRESTEndpoint.
+	RESTEndpoint string
+
+	// CABundle is an optional path to a PEM file containing additional
+	// CA certificates to trust.
+	CABundle string
+
+	// Proxy is an optional URL of a HTTP proxy. If not set, the standard
+	// HTTPS_PROXY/NO_PROXY environment variables are used.
+	Proxy string
+
+	// MaxRetries is the number of times a request is retried on transient
+	// errors and secondary rate limits. Defaults to 5.
+	MaxRetries int
+
+	// MinRateLimitRemaining is the rate limit budget below which the client
+	// pauses until the budget is reset. Defaults to DefaultMinRateLimitRemaining.
+	MinRateLimitRemaining int
 }
 
-func NewClient(ctx context.Context, log logrus.FieldLogger, token string) (*Client, error) {
-	if token == "" {
-		return nil, errors.New("token cannot be empty")
+func NewClient(ctx context.Context, log logrus.FieldLogger, opts ClientOptions) (*Client, error) {
+	if opts.Token == "" && opts.App == nil {
+		return nil, errors.New("either token or app credentials must be given")
 	}
 
-	src := oauth2.StaticTokenSource(
-		&oauth2.Token{
-			AccessToken: token,
+	baseTransport, err := NewTransport(opts.CABundle, opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := newRetryTransport(baseTransport, log, opts.MaxRetries)
+
+	var src oauth2.TokenSource
+
+	if opts.App != nil {
+		src, err = NewAppTokenSource(opts.RESTEndpoint, &http.Client{Transport: transport}, *opts.App)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup GitHub App authentication: %w", err)
+		}
+	} else {
+		src = oauth2.StaticTokenSource(
+			&oauth2.Token{
+				AccessToken: opts.Token,
+			},
+		)
+	}
+
+	httpClient := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: src,
+			Base:   transport,
 		},
-	)
-	httpClient := oauth2.NewClient(ctx, src)
-	client := githubv4.NewClient(httpClient)
+	}
+
+	endpoint := opts.GraphQLEndpoint
+	if endpoint == "" {
+		endpoint = DefaultGraphQLEndpoint
+	}
+
+	var client *githubv4.Client
+	if endpoint == DefaultGraphQLEndpoint {
+		client = githubv4.NewClient(httpClient)
+	} else {
+		client = githubv4.NewEnterpriseClient(endpoint, httpClient)
+	}
+
+	restEndpoint := opts.RESTEndpoint
+	if restEndpoint == "" {
+		restEndpoint = DefaultRESTEndpoint
+	}
+
+	minRemaining := opts.MinRateLimitRemaining
+	if minRemaining <= 0 {
+		minRemaining = DefaultMinRateLimitRemaining
+	}
 
 	return &Client{
-		ctx:    ctx,
-		client: client,
-		log:    log,
+		ctx:          ctx,
+		client:       client,
+		httpClient:   httpClient,
+		restEndpoint: strings.TrimSuffix(restEndpoint, "/"),
+		tokenSource:  src,
+		log:          log,
+		minRemaining: minRemaining,
+		stats: Stats{
+			Remaining: -1,
+		},
 	}, nil
 }
+
+// Token returns the currently valid access token. For GitHub Apps, this
+// mints a new installation token whenever the previous one is about to expire.
+func (c *Client) Token() (string, error) {
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
+
+// NewTransport returns a HTTP transport that trusts the system CAs plus the
+// optional CA bundle and uses the given proxy (or the environment's proxy
+// settings if no proxy is given).
+func NewTransport(caBundle string, proxy string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %q does not contain any valid certificates", caBundle)
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+)
+
+type addCommentQuery struct {
+	AddComment struct {
+		ClientMutationID string
+	} `graphql:"addComment(input: $input)"`
+}
+
+// CreateComment adds a comment to an issue or pull request, identified by
+// its node ID.
+func (c *Client) CreateComment(subjectID githubv4.ID, body string) error {
+	var q addCommentQuery
+
+	c.log.WithFields(logrus.Fields{
+		"subject": subjectID,
+	}).Debug("CreateComment()")
+
+	input := githubv4.AddCommentInput{
+		SubjectID: subjectID,
+		Body:      githubv4.String(body),
+	}
+
+	return wrapError(c.mutate(&q, input))
+}
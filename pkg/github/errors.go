@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// GitHub (Enterprise Server) reports unknown fields and arguments like this;
+// older GHES releases lack some of the fields this tool uses.
+var (
+	unknownFieldRegexp    = regexp.MustCompile(`Field '([^']+)' doesn't exist on type '([^']+)'`)
+	unknownArgumentRegexp = regexp.MustCompile(`Field '([^']+)' doesn't accept argument '([^']+)'`)
+)
+
+// UnsupportedSchemaError is returned when the GitHub server does not know
+// about a field or argument that is required by a query.
+type UnsupportedSchemaError struct {
+	// Missing describes what is missing, e.g. "field file on type Commit".
+	Missing string
+	err     error
+}
+
+func (e *UnsupportedSchemaError) Error() string {
+	return fmt.Sprintf("the GitHub server does not support the %s, it is probably an older GitHub Enterprise Server release: %v", e.Missing, e.err)
+}
+
+func (e *UnsupportedSchemaError) Unwrap() error {
+	return e.err
+}
+
+// asSchemaError returns an UnsupportedSchemaError if err indicates that the
+// GitHub server lacks a field, or nil otherwise.
+func asSchemaError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if match := unknownFieldRegexp.FindStringSubmatch(err.Error()); match != nil {
+		return &UnsupportedSchemaError{
+			Missing: fmt.Sprintf("field %q on type %q", match[1], match[2]),
+			err:     err,
+		}
+	}
+
+	if match := unknownArgumentRegexp.FindStringSubmatch(err.Error()); match != nil {
+		return &UnsupportedSchemaError{
+			Missing: fmt.Sprintf("argument %q on field %q", match[2], match[1]),
+			err:     err,
+		}
+	}
+
+	return nil
+}
+
+// wrapError turns schema errors into UnsupportedSchemaErrors and returns
+// all other errors unchanged.
+func wrapError(err error) error {
+	if schemaErr := asSchemaError(err); schemaErr != nil {
+		return schemaErr
+	}
+
+	return err
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAsSchemaError(t *testing.T) {
+	testcases := []struct {
+		err      error
+		expected string
+	}{
+		{
+			err:      nil,
+			expected: "",
+		},
+		{
+			err:      errors.New("Could not resolve to an Organization with the login of 'foo'."),
+			expected: "",
+		},
+		{
+			err:      errors.New("Field 'file' doesn't exist on type 'Commit'"),
+			expected: `field "file" on type "Commit"`,
+		},
+		{
+			err:      errors.New("Field 'refs' doesn't accept argument 'orderBy'"),
+			expected: `argument "orderBy" on field "refs"`,
+		},
+	}
+
+	for _, testcase := range testcases {
+		err := asSchemaError(testcase.err)
+
+		if testcase.expected == "" {
+			if err != nil {
+				t.Errorf("Expected no schema error for %v, but got %v.", testcase.err, err)
+			}
+
+			continue
+		}
+
+		var schemaErr *UnsupportedSchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Errorf("Expected schema error for %v, but got %v.", testcase.err, err)
+			continue
+		}
+
+		if schemaErr.Missing != testcase.expected {
+			t.Errorf("Expected %q, but got %q.", testcase.expected, schemaErr.Missing)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+)
+
+type branchHeadsQuery struct {
+	rateLimitQuery
+
+	Organization struct {
+		Repositories struct {
+			Nodes []struct {
+				ID   githubv4.ID
+				Name string
+				Refs struct {
+					Nodes []struct {
+						Name   string
+						Target struct {
+							OID string
+						}
+					}
+				} `graphql:"refs(first: 100, orderBy: {field: ALPHABETICAL, direction: ASC}, refPrefix: $prefix)"`
+			}
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage bool
+			}
+		} `graphql:"repositories(first: 50, orderBy: {field: NAME, direction: ASC}, after: $cursor)"`
+	} `graphql:"organization(login: $login)"`
+}
+
+// GetBranchHeads lists all repositories and the head commit of each of their
+// branches. This is much cheaper than GetRepositoriesAndBranches, but only the
+// Name and HeadOID of the returned branches are set.
+func (c *Client) GetBranchHeads(org string) ([]Repository, error) {
+	result := []Repository{}
+	cursor := ""
+
+	variables := map[string]interface{}{
+		"login":  githubv4.String(org),
+		"prefix": githubv4.String("refs/heads/"),
+		"cursor": (*githubv4.String)(nil),
+	}
+
+	for {
+		var q branchHeadsQuery
+
+		c.log.WithFields(logrus.Fields{
+			"org":    org,
+			"cursor": cursor,
+		}).Debug("GetBranchHeads()")
+
+		if err := c.query(&q, variables); err != nil {
+			return nil, err
+		}
+
+		for _, r := range q.Organization.Repositories.Nodes {
+			repo := Repository{
+				ID:       r.ID,
+				Name:     r.Name,
+				Branches: []Branch{},
+			}
+
+			for _, ref := range r.Refs.Nodes {
+				repo.Branches = append(repo.Branches, Branch{
+					Name:    ref.Name,
+					HeadOID: ref.Target.OID,
+				})
+			}
+
+			result = append(result, repo)
+		}
+
+		if !q.Organization.Repositories.PageInfo.HasNextPage {
+			break
+		}
+
+		cursor = string(q.Organization.Repositories.PageInfo.EndCursor)
+		variables["cursor"] = githubv4.String(cursor)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+
+	return result, nil
+}
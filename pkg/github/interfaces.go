@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// TeamLister lists all teams and their members in an organization.
+type TeamLister interface {
+	GetTeams(org string) ([]Team, error)
+}
+
+// RepositoryLister lists all repositories in an organization, including
+// their branches and the aliases file in each branch.
+type RepositoryLister interface {
+	GetRepositoriesAndBranches(org string, activity ActivityOptions) ([]Repository, error)
+	GetRepository(org, repo string, activity ActivityOptions) (*Repository, error)
+	GetBranchHeads(org string) ([]Repository, error)
+}
+
+// ActivityClient provides further signals for the activity on a branch,
+// besides its commits.
+type ActivityClient interface {
+	GetLastMergedPullRequest(org, repo, branch string) (time.Time, error)
+	GetLatestTag(org, repo, branch string, since time.Time) (time.Time, error)
+}
+
+// TeamMembershipClient lists and changes the members of teams.
+type TeamMembershipClient interface {
+	GetTeamMembers(org, team string, immediate bool) ([]string, error)
+	AddTeamMember(org, team, login string) error
+	RemoveTeamMember(org, team, login string) error
+}
+
+// OrganizationMemberChecker checks whether a user belongs to an
+// organization.
+type OrganizationMemberChecker interface {
+	IsOrganizationMember(org, login string) (bool, error)
+}
+
+// AliasesFileLister finds aliases files anywhere in a repository.
+type AliasesFileLister interface {
+	GetAliasesFiles(org, repo, oid string, patterns []string) (map[string]string, error)
+}
+
+// PullRequestClient finds and creates pull requests.
+type PullRequestClient interface {
+	GetPullRequestForBranch(org, repo, baseRef, headRef string) (int, error)
+	CreatePullRequest(repoID githubv4.ID, baseRef, headRef, title, body string) (int, error)
+	GetPullRequestBaseBranch(org, repo string, number int) (string, error)
+}
+
+// BranchProtectionChecker checks whether commits can be pushed directly
+// into a branch.
+type BranchProtectionChecker interface {
+	CanPushDirectly(org, repo, branch string, signed bool) (bool, string, error)
+}
+
+// Commenter creates comments on issues and pull requests.
+type Commenter interface {
+	CreateComment(subjectID githubv4.ID, body string) error
+}
+
+var (
+	_ TeamLister                = &Client{}
+	_ RepositoryLister          = &Client{}
+	_ ActivityClient            = &Client{}
+	_ AliasesFileLister         = &Client{}
+	_ TeamMembershipClient      = &Client{}
+	_ OrganizationMemberChecker = &Client{}
+	_ PullRequestClient         = &Client{}
+	_ BranchProtectionChecker   = &Client{}
+	_ Commenter                 = &Client{}
+)
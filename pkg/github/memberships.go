@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AddTeamMember adds a user to a team as a regular member. Users who are not
+// yet members of the organization are invited. Users who already are
+// members of the team (including pending invitations) are left alone, so
+// that maintainers are not demoted.
+func (c *Client) AddTeamMember(org, team, login string) error {
+	c.log.WithFields(logrus.Fields{
+		"org":   org,
+		"team":  team,
+		"login": login,
+	}).Debug("AddTeamMember()")
+
+	var membership struct {
+		State string `json:"state"`
+	}
+
+	err := c.restRequest(http.MethodGet, membershipPath(org, team, login), nil, &membership)
+	if err == nil {
+		c.log.WithFields(logrus.Fields{
+			"team":  team,
+			"login": login,
+			"state": membership.State,
+		}).Debug("User is already a member.")
+
+		return nil
+	}
+
+	if !isNotFound(err) {
+		return err
+	}
+
+	body := map[string]string{
+		"role": string(TeamRoleMember),
+	}
+
+	return c.restRequest(http.MethodPut, membershipPath(org, team, login), body, nil)
+}
+
+// RemoveTeamMember removes a user from a team; the user stays a member of
+// the organization.
+func (c *Client) RemoveTeamMember(org, team, login string) error {
+	c.log.WithFields(logrus.Fields{
+		"org":   org,
+		"team":  team,
+		"login": login,
+	}).Debug("RemoveTeamMember()")
+
+	return c.restRequest(http.MethodDelete, membershipPath(org, team, login), nil, nil)
+}
+
+// IsOrganizationMember returns true if the user is a (public or private)
+// member of the organization.
+func (c *Client) IsOrganizationMember(org, login string) (bool, error) {
+	c.log.WithFields(logrus.Fields{
+		"org":   org,
+		"login": login,
+	}).Debug("IsOrganizationMember()")
+
+	path := fmt.Sprintf("/orgs/%s/members/%s", url.PathEscape(org), url.PathEscape(login))
+
+	err := c.restRequest(http.MethodGet, path, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func membershipPath(org, team, login string) string {
+	return fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", url.PathEscape(org), url.PathEscape(team), url.PathEscape(login))
+}
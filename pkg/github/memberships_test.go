@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestAddTeamMember(t *testing.T) {
+	testcases := []struct {
+		name     string
+		existing bool
+		expected []string
+	}{
+		{
+			name:     "new member is added",
+			expected: []string{"GET", "PUT role=member"},
+		},
+		{
+			name:     "existing member keeps their role",
+			existing: true,
+			expected: []string{"GET"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			var (
+				lock     sync.Mutex
+				requests []string
+			)
+
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/orgs/org/teams/sig-a/memberships/alice" {
+					t.Errorf("Unexpected request: %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+
+				switch r.Method {
+				case http.MethodGet:
+					requests = append(requests, "GET")
+
+					if !testcase.existing {
+						w.WriteHeader(http.StatusNotFound)
+						fmt.Fprint(w, `{"message": "Not Found"}`)
+						return
+					}
+
+					fmt.Fprint(w, `{"role": "maintainer", "state": "active"}`)
+
+				case http.MethodPut:
+					var body map[string]string
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Errorf("Invalid request body: %v", err)
+					}
+
+					requests = append(requests, "PUT role="+body["role"])
+					fmt.Fprint(w, `{"role": "member", "state": "pending"}`)
+
+				default:
+					t.Errorf("Unexpected method: %s", r.Method)
+				}
+			})
+
+			if err := client.AddTeamMember("org", "sig-a", "alice"); err != nil {
+				t.Fatalf("Failed to add member: %v", err)
+			}
+
+			if diff := deep.Equal(requests, testcase.expected); diff != nil {
+				t.Errorf("requests not equal: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRemoveTeamMember(t *testing.T) {
+	removed := false
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/orgs/org/teams/sig-a/memberships/alice" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		removed = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.RemoveTeamMember("org", "sig-a", "alice"); err != nil {
+		t.Fatalf("Failed to remove member: %v", err)
+	}
+
+	if !removed {
+		t.Error("Member was not removed.")
+	}
+}
+
+func TestIsOrganizationMember(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/org/members/alice":
+			w.WriteHeader(http.StatusNoContent)
+		case "/orgs/org/members/mallory":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+
+	for login, expected := range map[string]bool{"alice": true, "mallory": false} {
+		member, err := client.IsOrganizationMember("org", login)
+		if err != nil {
+			t.Fatalf("Failed to check %s: %v", login, err)
+		}
+
+		if member != expected {
+			t.Errorf("Expected %s to be a member: %v, got %v.", login, expected, member)
+		}
+	}
+
+	if _, err := client.IsOrganizationMember("org", "bob"); err == nil {
+		t.Error("Expected an error for a failed request.")
+	}
+}
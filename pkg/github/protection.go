@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockingRules are the ruleset rules that prevent pushing a commit
+// directly into a branch.
+var blockingRules = map[string]string{
+	"pull_request":           "a ruleset requires pull requests",
+	"required_status_checks": "a ruleset requires status checks",
+	"required_deployments":   "a ruleset requires deployments",
+	"update":                 "a ruleset restricts updates",
+	"required_signatures":    "a ruleset requires signed commits",
+}
+
+type branchResponse struct {
+	Protected bool `json:"protected"`
+}
+
+type branchRule struct {
+	Type string `json:"type"`
+}
+
+// CanPushDirectly checks the branch protection and the rulesets of a
+// branch. If pushing commits directly into the branch is not allowed, the
+// reason is returned. Rulesets requiring signed commits only block unsigned
+// commits. Bypass lists are not taken into account.
+func (c *Client) CanPushDirectly(org, repo, branch string, signed bool) (bool, string, error) {
+	c.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"branch": branch,
+	}).Debug("CanPushDirectly()")
+
+	repoPath := fmt.Sprintf("/repos/%s/%s", url.PathEscape(org), url.PathEscape(repo))
+
+	// branch protection rules usually require status checks or reviews;
+	// their details are only visible to admins, so any rule is considered
+	// to block direct pushes
+	var b branchResponse
+	if err := c.restRequest(http.MethodGet, fmt.Sprintf("%s/branches/%s", repoPath, url.PathEscape(branch)), nil, &b); err != nil {
+		return false, "", fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	if b.Protected {
+		return false, "branch is protected", nil
+	}
+
+	var rules []branchRule
+	if err := c.restRequest(http.MethodGet, fmt.Sprintf("%s/rules/branches/%s", repoPath, url.PathEscape(branch)), nil, &rules); err != nil {
+		// older GitHub Enterprise Server versions do not support rulesets
+		if isNotFound(err) {
+			return true, "", nil
+		}
+
+		return false, "", fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		if rule.Type == "required_signatures" && signed {
+			continue
+		}
+
+		if reason, exists := blockingRules[rule.Type]; exists {
+			return false, reason, nil
+		}
+	}
+
+	return true, "", nil
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCanPushDirectly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo/branches/protected":
+			fmt.Fprint(w, `{"name": "protected", "protected": true}`)
+
+		case "/repos/org/repo/branches/ruleset", "/repos/org/repo/branches/open", "/repos/org/repo/branches/signed", "/repos/org/legacy/branches/main":
+			fmt.Fprint(w, `{"protected": false}`)
+
+		case "/repos/org/repo/rules/branches/ruleset":
+			fmt.Fprint(w, `[{"type": "deletion"}, {"type": "pull_request", "parameters": {}}]`)
+
+		case "/repos/org/repo/rules/branches/signed":
+			fmt.Fprint(w, `[{"type": "required_signatures"}]`)
+
+		case "/repos/org/repo/rules/branches/open":
+			fmt.Fprint(w, `[{"type": "deletion"}]`)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer server.Close()
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	client, err := NewClient(context.Background(), log, ClientOptions{
+		Token:        "secret",
+		RESTEndpoint: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	testcases := []struct {
+		repo     string
+		branch   string
+		signed   bool
+		expected bool
+		reason   string
+		invalid  bool
+	}{
+		{repo: "repo", branch: "protected", expected: false, reason: "branch is protected"},
+		{repo: "repo", branch: "ruleset", expected: false, reason: "a ruleset requires pull requests"},
+		{repo: "repo", branch: "open", expected: true},
+		{repo: "repo", branch: "signed", expected: false, reason: "a ruleset requires signed commits"},
+		{repo: "repo", branch: "signed", signed: true, expected: true},
+		// rulesets are not supported by the server
+		{repo: "legacy", branch: "main", expected: true},
+		{repo: "repo", branch: "missing", invalid: true},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.branch, func(t *testing.T) {
+			allowed, reason, err := client.CanPushDirectly("org", tt.repo, tt.branch, tt.signed)
+			if tt.invalid {
+				if err == nil {
+					t.Fatal("Expected an error, but got none.")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to check branch: %v", err)
+			}
+
+			if allowed != tt.expected || reason != tt.reason {
+				t.Fatalf("Expected (%v, %q), got (%v, %q).", tt.expected, tt.reason, allowed, reason)
+			}
+		})
+	}
+}
@@ -33,15 +33,17 @@ func (c *Client) CreatePullRequest(repoID githubv4.ID, baseRef, headRef, title,
 		MaintainerCanModify: githubv4.NewBoolean(true),
 	}
 
-	err := c.client.Mutate(c.ctx, &q, input, nil)
+	err := c.mutate(&q, input)
 	if err != nil {
-		return 0, err
+		return 0, wrapError(err)
 	}
 
 	return q.CreatePullRequest.PullRequest.Number, nil
 }
 
 type pullRequestsQuery struct {
+	rateLimitQuery
+
 	Organization struct {
 		Repository struct {
 			PullRequests struct {
@@ -70,9 +72,9 @@ func (c *Client) GetPullRequestForBranch(org, repo, baseRef, headRef string) (in
 		"head": headRef,
 	}).Debug("GetPullRequestForBranch()")
 
-	err := c.client.Query(c.ctx, &q, variables)
+	err := c.query(&q, variables)
 	if err != nil {
-		return 0, err
+		return 0, wrapError(err)
 	}
 
 	if len(q.Organization.Repository.PullRequests.Nodes) == 0 {
@@ -81,3 +83,36 @@ func (c *Client) GetPullRequestForBranch(org, repo, baseRef, headRef string) (in
 
 	return q.Organization.Repository.PullRequests.Nodes[0].Number, nil
 }
+
+type pullRequestBaseQuery struct {
+	rateLimitQuery
+
+	Repository struct {
+		PullRequest struct {
+			BaseRefName string
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $login, name: $repo)"`
+}
+
+func (c *Client) GetPullRequestBaseBranch(org, repo string, number int) (string, error) {
+	variables := map[string]interface{}{
+		"login":  githubv4.String(org),
+		"repo":   githubv4.String(repo),
+		"number": githubv4.Int(number),
+	}
+
+	var q pullRequestBaseQuery
+
+	c.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"number": number,
+	}).Debug("GetPullRequestBaseBranch()")
+
+	err := c.query(&q, variables)
+	if err != nil {
+		return "", wrapError(err)
+	}
+
+	return q.Repository.PullRequest.BaseRefName, nil
+}
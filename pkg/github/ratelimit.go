@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMinRateLimitRemaining is the number of points that should be left
+// in the rate limit budget before the client pauses until the budget resets.
+const DefaultMinRateLimitRemaining = 100
+
+// how often to wait for the rate limit reset before giving up on a query
+const maxRateLimitAttempts = 3
+
+// rateLimitQuery is embedded into all queries to keep track of the rate limit.
+type rateLimitQuery struct {
+	RateLimit struct {
+		Cost      int
+		Remaining int
+		ResetAt   githubv4.DateTime
+	}
+}
+
+func (q *rateLimitQuery) rateLimit() *rateLimitQuery {
+	return q
+}
+
+type rateLimited interface {
+	rateLimit() *rateLimitQuery
+}
+
+// Stats contains information about the API usage of a Client.
+type Stats struct {
+	// Requests is the number of GraphQL and REST requests sent.
+	Requests int
+	// Cost is the sum of all rate limit points consumed by queries.
+	Cost int
+	// Remaining is the remaining rate limit budget as of the last query
+	// (-1 if unknown).
+	Remaining int
+	// ResetAt is the time when the rate limit budget is reset.
+	ResetAt time.Time
+}
+
+func (c *Client) Stats() Stats {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	return c.stats
+}
+
+// query runs a GraphQL query, waiting for the rate limit budget to reset
+// first if required.
+func (c *Client) query(q interface{}, variables map[string]interface{}) error {
+	for attempt := 1; ; attempt++ {
+		if err := c.waitForRateLimit(); err != nil {
+			return err
+		}
+
+		err := c.client.Query(c.ctx, q, variables)
+
+		c.statsLock.Lock()
+		c.stats.Requests++
+
+		if rl, ok := q.(rateLimited); ok && !rl.rateLimit().RateLimit.ResetAt.IsZero() {
+			info := rl.rateLimit().RateLimit
+
+			c.stats.Cost += info.Cost
+			c.stats.Remaining = info.Remaining
+			c.stats.ResetAt = info.ResetAt.Time
+		}
+		c.statsLock.Unlock()
+
+		if !isPrimaryRateLimitError(err) || attempt >= maxRateLimitAttempts {
+			return err
+		}
+
+		// the budget is exhausted, force waiting for the reset
+		c.statsLock.Lock()
+		c.stats.Remaining = 0
+		if c.stats.ResetAt.Before(time.Now()) {
+			c.stats.ResetAt = time.Now().Add(time.Minute)
+		}
+		c.statsLock.Unlock()
+	}
+}
+
+func (c *Client) mutate(m interface{}, input githubv4.Input) error {
+	if err := c.waitForRateLimit(); err != nil {
+		return err
+	}
+
+	c.statsLock.Lock()
+	c.stats.Requests++
+	c.statsLock.Unlock()
+
+	return c.client.Mutate(c.ctx, m, input, nil)
+}
+
+func (c *Client) waitForRateLimit() error {
+	c.statsLock.Lock()
+	remaining := c.stats.Remaining
+	resetAt := c.stats.ResetAt
+	c.statsLock.Unlock()
+
+	if remaining < 0 || remaining >= c.minRemaining || resetAt.Before(time.Now()) {
+		return nil
+	}
+
+	wait := time.Until(resetAt) + time.Second
+
+	c.log.WithFields(logrus.Fields{
+		"remaining": remaining,
+		"reset":     resetAt.Format(time.RFC3339),
+	}).Warnf("Rate limit budget is low, pausing for %v…", wait.Round(time.Second))
+
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+func isPrimaryRateLimitError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "API rate limit exceeded")
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RESTError is returned for unsuccessful responses from the REST API.
+type RESTError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *RESTError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+// isNotFound returns true if the REST API responded with 404.
+func isNotFound(err error) bool {
+	var restErr *RESTError
+
+	return errors.As(err, &restErr) && restErr.StatusCode == http.StatusNotFound
+}
+
+// restRequest sends a request to the REST API and decodes the JSON response
+// into dest, unless dest is nil.
+func (c *Client) restRequest(method string, path string, body interface{}, dest interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.restEndpoint+path, reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.statsLock.Lock()
+	c.stats.Requests++
+	c.statsLock.Unlock()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &RESTError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(respBody)),
+		}
+	}
+
+	if dest == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(respBody, dest); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
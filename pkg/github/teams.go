@@ -4,6 +4,7 @@
 package github
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 
@@ -13,13 +14,22 @@ import (
 
 // organization.teams do not support pagination, neither does the members collection
 type teamMembersQuery struct {
+	rateLimitQuery
+
 	Organization struct {
 		Teams struct {
 			Nodes []struct {
-				Name    string
+				Slug       string
+				Name       string
+				ParentTeam *struct {
+					Slug string
+				}
 				Members struct {
-					Nodes []struct {
-						Login string
+					Edges []struct {
+						Role githubv4.TeamMemberRole
+						Node struct {
+							Login string
+						}
 					}
 				} `graphql:"members(first: 100, orderBy: {field: LOGIN, direction: ASC})"`
 			}
@@ -27,9 +37,23 @@ type teamMembersQuery struct {
 	} `graphql:"organization(login: $login)"`
 }
 
+// TeamRole is the role of a member in a team.
+type TeamRole string
+
+const (
+	TeamRoleMember     TeamRole = "member"
+	TeamRoleMaintainer TeamRole = "maintainer"
+)
+
 type Team struct {
-	Slug    string
+	Slug string
+	// Name is the display name of the team.
+	Name string
+	// Parent is the slug of the parent team, if any.
+	Parent  string
 	Members []string
+	// Roles maps the members' logins to their role in the team.
+	Roles map[string]TeamRole
 }
 
 func (c *Client) GetTeams(org string) ([]Team, error) {
@@ -43,22 +67,35 @@ func (c *Client) GetTeams(org string) ([]Team, error) {
 		"org": org,
 	}).Debug("GetTeams()")
 
-	err := c.client.Query(c.ctx, &q, variables)
+	err := c.query(&q, variables)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	result := []Team{}
 	for _, t := range q.Organization.Teams.Nodes {
-		members := []string{}
-		for _, m := range t.Members.Nodes {
-			members = append(members, m.Login)
+		team := Team{
+			Slug:    t.Slug,
+			Name:    t.Name,
+			Members: []string{},
+			Roles:   map[string]TeamRole{},
+		}
+
+		if t.ParentTeam != nil {
+			team.Parent = t.ParentTeam.Slug
+		}
+
+		for _, m := range t.Members.Edges {
+			team.Members = append(team.Members, m.Node.Login)
+
+			if m.Role == githubv4.TeamMemberRoleMaintainer {
+				team.Roles[m.Node.Login] = TeamRoleMaintainer
+			} else {
+				team.Roles[m.Node.Login] = TeamRoleMember
+			}
 		}
 
-		result = append(result, Team{
-			Slug:    t.Name,
-			Members: members,
-		})
+		result = append(result, team)
 	}
 
 	sort.Slice(result, func(i, j int) bool {
@@ -67,3 +104,73 @@ func (c *Client) GetTeams(org string) ([]Team, error) {
 
 	return result, nil
 }
+
+type teamMembersPageQuery struct {
+	rateLimitQuery
+
+	Organization struct {
+		Team *struct {
+			Members struct {
+				Nodes []struct {
+					Login string
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage bool
+				}
+			} `graphql:"members(first: 100, membership: $membership, orderBy: {field: LOGIN, direction: ASC}, after: $cursor)"`
+		} `graphql:"team(slug: $slug)"`
+	} `graphql:"organization(login: $login)"`
+}
+
+// GetTeamMembers lists all members of a team, without the limit of GetTeams.
+// If immediate is set, members who only belong to the team because they are
+// members of a child team are not included.
+func (c *Client) GetTeamMembers(org, team string, immediate bool) ([]string, error) {
+	membership := githubv4.TeamMembershipTypeAll
+	if immediate {
+		membership = githubv4.TeamMembershipTypeImmediate
+	}
+
+	result := []string{}
+	cursor := ""
+
+	variables := map[string]interface{}{
+		"login":      githubv4.String(org),
+		"slug":       githubv4.String(team),
+		"membership": membership,
+		"cursor":     (*githubv4.String)(nil),
+	}
+
+	for {
+		var q teamMembersPageQuery
+
+		c.log.WithFields(logrus.Fields{
+			"org":       org,
+			"team":      team,
+			"immediate": immediate,
+			"cursor":    cursor,
+		}).Debug("GetTeamMembers()")
+
+		if err := c.query(&q, variables); err != nil {
+			return nil, wrapError(err)
+		}
+
+		if q.Organization.Team == nil {
+			return nil, fmt.Errorf("team %s/%s not found", org, team)
+		}
+
+		for _, m := range q.Organization.Team.Members.Nodes {
+			result = append(result, m.Login)
+		}
+
+		if !q.Organization.Team.Members.PageInfo.HasNextPage {
+			break
+		}
+
+		cursor = string(q.Organization.Team.Members.PageInfo.EndCursor)
+		variables["cursor"] = githubv4.String(cursor)
+	}
+
+	return result, nil
+}
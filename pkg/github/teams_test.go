@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/sirupsen/logrus"
+)
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// newTestClient returns a client that talks to the given handler for both
+// the GraphQL and the REST API.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	client, err := NewClient(context.Background(), log, ClientOptions{
+		Token:           "secret",
+		GraphQLEndpoint: server.URL + "/graphql",
+		RESTEndpoint:    server.URL + "/",
+		MaxRetries:      1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	return client
+}
+
+func TestGetTeams(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"organization": {"teams": {"nodes": [
+			{"slug": "sig-b", "name": "SIG B", "members": {"edges": []}},
+			{"slug": "sig-a", "name": "SIG A", "parentTeam": {"slug": "sigs"}, "members": {"edges": [
+				{"role": "MAINTAINER", "node": {"login": "alice"}},
+				{"role": "MEMBER", "node": {"login": "bob"}}
+			]}}
+		]}}}}`)
+	})
+
+	teams, err := client.GetTeams("org")
+	if err != nil {
+		t.Fatalf("Failed to list teams: %v", err)
+	}
+
+	expected := []Team{
+		{
+			Slug:    "sig-a",
+			Name:    "SIG A",
+			Parent:  "sigs",
+			Members: []string{"alice", "bob"},
+			Roles:   map[string]TeamRole{"alice": TeamRoleMaintainer, "bob": TeamRoleMember},
+		},
+		{
+			Slug:    "sig-b",
+			Name:    "SIG B",
+			Members: []string{},
+			Roles:   map[string]TeamRole{},
+		},
+	}
+
+	if diff := deep.Equal(teams, expected); diff != nil {
+		t.Errorf("teams not equal: %v", diff)
+	}
+}
+
+func TestGetTeamMembers(t *testing.T) {
+	pages := map[string]string{
+		"":      `{"nodes": [{"login": "alice"}, {"login": "bob"}], "pageInfo": {"endCursor": "page2", "hasNextPage": true}}`,
+		"page2": `{"nodes": [{"login": "carol"}], "pageInfo": {"endCursor": "page3", "hasNextPage": false}}`,
+	}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Invalid request: %v", err)
+		}
+
+		if req.Variables["slug"] != "sig-a" || req.Variables["membership"] != "IMMEDIATE" {
+			t.Errorf("Unexpected variables: %v", req.Variables)
+		}
+
+		cursor, _ := req.Variables["cursor"].(string)
+
+		page, exists := pages[cursor]
+		if !exists {
+			t.Fatalf("Unexpected cursor %q.", cursor)
+		}
+
+		fmt.Fprintf(w, `{"data": {"organization": {"team": {"members": %s}}}}`, page)
+	})
+
+	members, err := client.GetTeamMembers("org", "sig-a", true)
+	if err != nil {
+		t.Fatalf("Failed to list members: %v", err)
+	}
+
+	if diff := deep.Equal(members, []string{"alice", "bob", "carol"}); diff != nil {
+		t.Errorf("members not equal: %v", diff)
+	}
+
+	if requests := client.Stats().Requests; requests != 2 {
+		t.Errorf("Expected 2 requests, got %d.", requests)
+	}
+}
+
+func TestGetTeamMembersMissingTeam(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"organization": {"team": null}}}`)
+	})
+
+	if _, err := client.GetTeamMembers("org", "sig-z", false); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error, got %v.", err)
+	}
+}
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = 2 * time.Second
+	maxBackoff            = 2 * time.Minute
+)
+
+// retryTransport retries requests that failed because of network issues,
+// transient server errors or GitHub's secondary rate limits. Requests that
+// modify data (GraphQL mutations and all REST requests except GET) are only
+// retried on secondary rate limits and 429 Too Many Requests responses: GitHub
+// also returns 5xx errors for requests that timed out, but might still have
+// been processed, so retrying them could e.g. create duplicate pull requests.
+type retryTransport struct {
+	base           http.RoundTripper
+	log            logrus.FieldLogger
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+func newRetryTransport(base http.RoundTripper, log logrus.FieldLogger, maxRetries int) *retryTransport {
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	return &retryTransport{
+		base:           base,
+		log:            log,
+		maxRetries:     maxRetries,
+		initialBackoff: defaultInitialBackoff,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.initialBackoff
+	idempotent := isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+
+		var wait time.Duration
+		var reason string
+
+		switch {
+		case err != nil:
+			if !idempotent {
+				return resp, err
+			}
+
+			wait, reason = backoff, err.Error()
+
+		case resp.StatusCode >= 500:
+			if !idempotent {
+				return resp, nil
+			}
+
+			wait, reason = backoff, resp.Status
+
+		case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+			var limited bool
+
+			resp, limited, wait = checkSecondaryRateLimit(resp, backoff)
+			if !limited && resp.StatusCode != http.StatusTooManyRequests {
+				return resp, nil
+			}
+
+			if !limited {
+				wait = backoff
+			}
+
+			reason = "secondary rate limit"
+
+		default:
+			return resp, nil
+		}
+
+		if attempt >= t.maxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.log.WithFields(logrus.Fields{
+			"attempt": attempt + 1,
+			"reason":  reason,
+			"wait":    wait,
+		}).Warn("GitHub request failed, retrying…")
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// isIdempotent returns true if the request can safely be sent again, even if
+// GitHub might already have processed it.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		return isGraphQLQuery(req)
+	default:
+		return false
+	}
+}
+
+// isGraphQLQuery returns true if the request is a GraphQL query, as opposed
+// to a mutation or a REST request.
+func isGraphQLQuery(req *http.Request) bool {
+	if req.GetBody == nil {
+		return false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	defer body.Close()
+
+	var payload struct {
+		Query string `json:"query"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return false
+	}
+
+	query := strings.TrimSpace(payload.Query)
+
+	return query != "" && !strings.HasPrefix(query, "mutation")
+}
+
+// checkSecondaryRateLimit determines whether a 403/429 response was caused by
+// a (secondary) rate limit and how long to wait before trying again. As the
+// body has to be read for this, a new response with a fresh body is returned.
+func checkSecondaryRateLimit(resp *http.Response, backoff time.Duration) (*http.Response, bool, time.Duration) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err != nil {
+		return resp, false, 0
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return resp, true, time.Duration(seconds) * time.Second
+	}
+
+	if resp.Header.Get("X-Ratelimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			return resp, true, time.Until(time.Unix(reset, 0)) + time.Second
+		}
+	}
+
+	if strings.Contains(strings.ToLower(string(body)), "rate limit") {
+		// GitHub recommends to wait at least one minute if no header is given
+		if backoff < time.Minute {
+			backoff = time.Minute
+		}
+
+		return resp, true, backoff
+	}
+
+	return resp, false, 0
+}
+
+// isTransportError returns true if the GraphQL request did not even yield a
+// GraphQL response, i.e. the request failed on the network or HTTP level.
+func isTransportError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+
+	// this is how shurcooL/graphql reports non-200 responses
+	return strings.HasPrefix(err.Error(), "non-200 OK status code")
+}
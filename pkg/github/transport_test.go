@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testQuery    = `{"query":"query{viewer{login}}"}`
+	testMutation = `{"query":"mutation($input:AddCommentInput!){addComment(input: $input){clientMutationId}}"}`
+)
+
+func TestRetryTransport(t *testing.T) {
+	testcases := []struct {
+		name      string
+		method    string
+		payload   string
+		responses []func(w http.ResponseWriter)
+		expected  int
+		attempts  int
+	}{
+		{
+			name: "success",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+			},
+			expected: http.StatusOK,
+			attempts: 1,
+		},
+		{
+			name: "transient server errors",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+			},
+			expected: http.StatusOK,
+			attempts: 3,
+		},
+		{
+			name: "secondary rate limit",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+				},
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+			},
+			expected: http.StatusOK,
+			attempts: 2,
+		},
+		{
+			name: "permission errors are not retried",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, `{"message":"Resource not accessible by integration"}`)
+				},
+			},
+			expected: http.StatusForbidden,
+			attempts: 1,
+		},
+		{
+			name: "give up eventually",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			},
+			expected: http.StatusInternalServerError,
+			attempts: 3,
+		},
+		{
+			name:    "mutations are not retried on server errors",
+			payload: testMutation,
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+			},
+			expected: http.StatusServiceUnavailable,
+			attempts: 1,
+		},
+		{
+			name:    "mutations are not retried on bad gateway",
+			payload: testMutation,
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			},
+			expected: http.StatusBadGateway,
+			attempts: 1,
+		},
+		{
+			name:    "mutations are retried on secondary rate limits",
+			payload: testMutation,
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusForbidden)
+				},
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+			},
+			expected: http.StatusOK,
+			attempts: 2,
+		},
+		{
+			name:    "REST writes are not retried on server errors",
+			method:  http.MethodPut,
+			payload: `{"role":"member"}`,
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			},
+			expected: http.StatusInternalServerError,
+			attempts: 1,
+		},
+		{
+			name:    "REST writes are retried on too many requests",
+			payload: `{"title":"Update aliases"}`,
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusCreated) },
+			},
+			expected: http.StatusCreated,
+			attempts: 2,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			method := testcase.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			payload := testcase.payload
+			if payload == "" {
+				payload = testQuery
+			}
+
+			attempts := 0
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != payload {
+					t.Errorf("Attempt %d: expected request body to be resent, got %q.", attempts+1, string(body))
+				}
+
+				testcase.responses[attempts](w)
+				attempts++
+			}))
+			defer server.Close()
+
+			transport := newRetryTransport(http.DefaultTransport, logrus.New(), 2)
+			transport.initialBackoff = 0
+
+			req, err := http.NewRequestWithContext(context.Background(), method, server.URL, strings.NewReader(payload))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != testcase.expected {
+				t.Errorf("Expected status %d, got %d.", testcase.expected, resp.StatusCode)
+			}
+
+			if attempts != testcase.attempts {
+				t.Errorf("Expected %d attempts, got %d.", testcase.attempts, attempts)
+			}
+		})
+	}
+}
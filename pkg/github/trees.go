@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+type treeResponse struct {
+	Tree []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"tree"`
+	Truncated bool `json:"truncated"`
+}
+
+type blobResponse struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// GetAliasesFiles lists all files in the given commit whose paths match any
+// of the patterns (see prow.MatchPath) and returns their content by path.
+// The files are discovered using the git trees API, as GraphQL offers no
+// way to list a tree recursively.
+func (c *Client) GetAliasesFiles(org, repo, oid string, patterns []string) (map[string]string, error) {
+	c.log.WithFields(logrus.Fields{
+		"org":  org,
+		"repo": repo,
+		"oid":  oid,
+	}).Debug("GetAliasesFiles()")
+
+	var tree treeResponse
+	if err := c.restRequest(http.MethodGet, fmt.Sprintf("/repos/%s/%s/git/trees/%s?recursive=1", url.PathEscape(org), url.PathEscape(repo), url.PathEscape(oid)), nil, &tree); err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	// GitHub limits the recursive listing to 100k entries / 7MB
+	if tree.Truncated {
+		c.log.WithFields(logrus.Fields{
+			"org":  org,
+			"repo": repo,
+		}).Warn("Repository tree is too large and was truncated, some aliases files might not be found.")
+	}
+
+	files := map[string]string{}
+	for _, entry := range tree.Tree {
+		if entry.Type != "blob" || !matchesAny(patterns, entry.Path) {
+			continue
+		}
+
+		var blob blobResponse
+		if err := c.restRequest(http.MethodGet, fmt.Sprintf("/repos/%s/%s/git/blobs/%s", url.PathEscape(org), url.PathEscape(repo), url.PathEscape(entry.SHA)), nil, &blob); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", entry.Path, err)
+		}
+
+		if blob.Encoding != "base64" {
+			return nil, fmt.Errorf("failed to fetch %s: unexpected encoding %q", entry.Path, blob.Encoding)
+		}
+
+		content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(blob.Content, "\n", ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", entry.Path, err)
+		}
+
+		files[entry.Path] = string(content)
+	}
+
+	return files, nil
+}
+
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if prow.MatchPath(pattern, path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/sirupsen/logrus"
+)
+
+func TestGetAliasesFiles(t *testing.T) {
+	blobs := map[string]string{
+		"sha-root": "aliases:\n  root: [alice]\n",
+		"sha-docs": "aliases:\n  docs: [bob]\n",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Request is not authenticated: %s", r.URL.Path)
+		}
+
+		switch r.URL.Path {
+		case "/repos/org/repo/git/trees/abc":
+			if r.URL.Query().Get("recursive") != "1" {
+				t.Error("Tree is not listed recursively.")
+			}
+
+			fmt.Fprint(w, `{"tree": [
+				{"path": "OWNERS_ALIASES", "type": "blob", "sha": "sha-root"},
+				{"path": "docs", "type": "tree", "sha": "sha-dir"},
+				{"path": "docs/OWNERS_ALIASES", "type": "blob", "sha": "sha-docs"},
+				{"path": "docs/OWNERS", "type": "blob", "sha": "sha-owners"}
+			]}`)
+
+		case "/repos/org/repo/git/blobs/sha-root", "/repos/org/repo/git/blobs/sha-docs":
+			sha := r.URL.Path[len("/repos/org/repo/git/blobs/"):]
+			fmt.Fprintf(w, `{"content": %q, "encoding": "base64"}`, base64.StdEncoding.EncodeToString([]byte(blobs[sha])))
+
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	client, err := NewClient(context.Background(), log, ClientOptions{
+		Token:        "secret",
+		RESTEndpoint: server.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	files, err := client.GetAliasesFiles("org", "repo", "abc", []string{"**/OWNERS_ALIASES"})
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+
+	expected := map[string]string{
+		"OWNERS_ALIASES":      blobs["sha-root"],
+		"docs/OWNERS_ALIASES": blobs["sha-docs"],
+	}
+
+	if diff := deep.Equal(files, expected); diff != nil {
+		t.Errorf("files not equal: %v", diff)
+	}
+
+	if requests := client.Stats().Requests; requests != 3 {
+		t.Errorf("Expected 3 requests, got %d.", requests)
+	}
+}
@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package logins contains helpers for GitHub logins and similar identifiers,
+// which are compared case-insensitively.
+package logins
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// LowerSet returns the lowercased values as a set.
+func LowerSet(values []string) sets.Set[string] {
+	result := sets.New[string]()
+	for _, v := range values {
+		result.Insert(strings.ToLower(v))
+	}
+
+	return result
+}
@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package metrics provides Prometheus metrics for synchronization runs.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+const namespace = "prow_aliases_syncer"
+
+// StatsSource provides the API usage statistics of a GitHub client.
+type StatsSource interface {
+	Stats() github.Stats
+}
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	runs               prometheus.Counter
+	failedRuns         prometheus.Counter
+	lastSuccess        prometheus.Gauge
+	repositories       prometheus.Counter
+	branches           *prometheus.CounterVec
+	outOfSync          prometheus.Counter
+	pullRequests       prometheus.Counter
+	pushes             prometheus.Counter
+	phaseDuration      *prometheus.HistogramVec
+	apiRequests        prometheus.CounterFunc
+	apiCost            prometheus.CounterFunc
+	rateLimitRemaining prometheus.GaugeFunc
+}
+
+// New creates and registers all metrics. The API usage is read from the
+// given client whenever the metrics are gathered.
+func New(client StatsSource) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		runs: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_total",
+			Help:      "Total number of synchronization runs.",
+		}),
+		failedRuns: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_failed_total",
+			Help:      "Number of synchronization runs that failed entirely or for at least one branch.",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful synchronization run.",
+		}),
+		repositories: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repositories_scanned_total",
+			Help:      "Number of repositories that were scanned.",
+		}),
+		branches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "branches_scanned_total",
+			Help:      "Number of branches that were scanned, by the final decision made for them.",
+		}, []string{"decision"}),
+		outOfSync: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "branches_out_of_sync_total",
+			Help:      "Number of branches whose aliases file was not in sync.",
+		}),
+		pullRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pull_requests_created_total",
+			Help:      "Number of pull requests that were created.",
+		}),
+		pushes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pushes_total",
+			Help:      "Number of branches that were pushed to GitHub.",
+		}),
+		phaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "phase_duration_seconds",
+			Help:      "Duration of the phases of a synchronization run.",
+			Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800},
+		}, []string{"phase"}),
+		apiRequests: prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "github_api_requests_total",
+			Help:      "Number of GitHub API requests (GraphQL and REST) sent.",
+		}, func() float64 {
+			return float64(client.Stats().Requests)
+		}),
+		apiCost: prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "github_api_cost_total",
+			Help:      "Sum of the rate limit points consumed by GraphQL requests.",
+		}, func() float64 {
+			return float64(client.Stats().Cost)
+		}),
+		rateLimitRemaining: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "github_rate_limit_remaining",
+			Help:      "Remaining GraphQL rate limit points as of the last request (-1 if unknown).",
+		}, func() float64 {
+			return float64(client.Stats().Remaining)
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.runs,
+		m.failedRuns,
+		m.lastSuccess,
+		m.repositories,
+		m.branches,
+		m.outOfSync,
+		m.pullRequests,
+		m.pushes,
+		m.phaseDuration,
+		m.apiRequests,
+		m.apiCost,
+		m.rateLimitRemaining,
+	)
+
+	return m
+}
+
+// Instrument sets the event and phase callbacks in the syncer options,
+// keeping any previously configured callbacks.
+func (m *Metrics) Instrument(opts *syncer.Options) {
+	onEvent := opts.OnEvent
+	opts.OnEvent = func(e syncer.Event) {
+		m.ObserveEvent(e)
+
+		if onEvent != nil {
+			onEvent(e)
+		}
+	}
+
+	onPhase := opts.OnPhase
+	opts.OnPhase = func(phase syncer.Phase, duration time.Duration) {
+		m.ObservePhase(phase, duration)
+
+		if onPhase != nil {
+			onPhase(phase, duration)
+		}
+	}
+}
+
+// ObserveEvent records a single decision of the syncer.
+func (m *Metrics) ObserveEvent(e syncer.Event) {
+	switch e.Decision {
+	case syncer.DecisionOutOfSync:
+		m.outOfSync.Inc()
+		// this is not a final decision, so it is not counted as a branch
+		return
+
+	case syncer.DecisionPullRequestCreated:
+		m.pullRequests.Inc()
+	}
+
+	m.branches.WithLabelValues(string(e.Decision)).Inc()
+}
+
+func (m *Metrics) ObservePhase(phase syncer.Phase, duration time.Duration) {
+	m.phaseDuration.WithLabelValues(string(phase)).Observe(duration.Seconds())
+}
+
+// ObserveRun records the outcome of a synchronization run.
+func (m *Metrics) ObserveRun(result *syncer.Result, err error) {
+	m.runs.Inc()
+
+	if result != nil {
+		m.repositories.Add(float64(result.Repositories))
+		m.pushes.Add(float64(result.Pushes))
+	}
+
+	if err != nil || (result != nil && result.Failed()) {
+		m.failedRuns.Inc()
+		return
+	}
+
+	m.lastSuccess.SetToCurrentTime()
+}
+
+// Handler returns a HTTP handler exposing the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Push sends all metrics to a Pushgateway, replacing all metrics previously
+// pushed with the same job name.
+func (m *Metrics) Push(url string, job string) error {
+	if err := push.New(url, job).Gatherer(m.registry).Push(); err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+type fakeStats struct {
+	stats github.Stats
+}
+
+func (f *fakeStats) Stats() github.Stats {
+	return f.stats
+}
+
+func TestInstrument(t *testing.T) {
+	stats := &fakeStats{stats: github.Stats{Requests: 3, Cost: 5, Remaining: 4995}}
+	m := New(stats)
+
+	var events []syncer.Event
+
+	opts := syncer.Options{
+		OnEvent: func(e syncer.Event) {
+			events = append(events, e)
+		},
+	}
+	m.Instrument(&opts)
+
+	opts.OnEvent(syncer.Event{Decision: syncer.DecisionUpToDate})
+	opts.OnEvent(syncer.Event{Decision: syncer.DecisionOutOfSync})
+	opts.OnEvent(syncer.Event{Decision: syncer.DecisionPullRequestCreated})
+	opts.OnPhase(syncer.PhaseTeams, 2*time.Second)
+
+	if len(events) != 3 {
+		t.Errorf("Expected the original callback to be called 3 times, got %d.", len(events))
+	}
+
+	m.ObserveRun(&syncer.Result{Repositories: 2, Pushes: 1}, nil)
+	m.ObserveRun(nil, errors.New("boom"))
+
+	expected := `
+# HELP prow_aliases_syncer_branches_out_of_sync_total Number of branches whose aliases file was not in sync.
+# TYPE prow_aliases_syncer_branches_out_of_sync_total counter
+prow_aliases_syncer_branches_out_of_sync_total 1
+# HELP prow_aliases_syncer_branches_scanned_total Number of branches that were scanned, by the final decision made for them.
+# TYPE prow_aliases_syncer_branches_scanned_total counter
+prow_aliases_syncer_branches_scanned_total{decision="pull-request-created"} 1
+prow_aliases_syncer_branches_scanned_total{decision="up-to-date"} 1
+# HELP prow_aliases_syncer_github_api_requests_total Number of GitHub API requests (GraphQL and REST) sent.
+# TYPE prow_aliases_syncer_github_api_requests_total counter
+prow_aliases_syncer_github_api_requests_total 3
+# HELP prow_aliases_syncer_github_rate_limit_remaining Remaining GraphQL rate limit points as of the last request (-1 if unknown).
+# TYPE prow_aliases_syncer_github_rate_limit_remaining gauge
+prow_aliases_syncer_github_rate_limit_remaining 4995
+# HELP prow_aliases_syncer_pull_requests_created_total Number of pull requests that were created.
+# TYPE prow_aliases_syncer_pull_requests_created_total counter
+prow_aliases_syncer_pull_requests_created_total 1
+# HELP prow_aliases_syncer_pushes_total Number of branches that were pushed to GitHub.
+# TYPE prow_aliases_syncer_pushes_total counter
+prow_aliases_syncer_pushes_total 1
+# HELP prow_aliases_syncer_repositories_scanned_total Number of repositories that were scanned.
+# TYPE prow_aliases_syncer_repositories_scanned_total counter
+prow_aliases_syncer_repositories_scanned_total 2
+# HELP prow_aliases_syncer_runs_failed_total Number of synchronization runs that failed entirely or for at least one branch.
+# TYPE prow_aliases_syncer_runs_failed_total counter
+prow_aliases_syncer_runs_failed_total 1
+# HELP prow_aliases_syncer_runs_total Total number of synchronization runs.
+# TYPE prow_aliases_syncer_runs_total counter
+prow_aliases_syncer_runs_total 2
+`
+
+	names := []string{
+		"prow_aliases_syncer_branches_out_of_sync_total",
+		"prow_aliases_syncer_branches_scanned_total",
+		"prow_aliases_syncer_github_api_requests_total",
+		"prow_aliases_syncer_github_rate_limit_remaining",
+		"prow_aliases_syncer_pull_requests_created_total",
+		"prow_aliases_syncer_pushes_total",
+		"prow_aliases_syncer_repositories_scanned_total",
+		"prow_aliases_syncer_runs_failed_total",
+		"prow_aliases_syncer_runs_total",
+	}
+
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(m.phaseDuration); count != 1 {
+		t.Errorf("Expected 1 phase duration series, got %d.", count)
+	}
+}
@@ -0,0 +1,276 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package plugin implements a Prow external plugin that synchronizes the
+// aliases file of a single repository when requested via a comment.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+	"go.xrstf.de/prow-aliases-syncer/pkg/webhook"
+)
+
+const (
+	Name    = "prow-aliases-syncer"
+	Command = "/sync-aliases"
+)
+
+var commandRegexp = regexp.MustCompile(`(?m)^/sync-aliases\s*$`)
+
+// Runner performs a synchronization for the given scope.
+type Runner interface {
+	RunScoped(ctx context.Context, scope syncer.Scope) (*syncer.Result, error)
+}
+
+type Clients struct {
+	PullRequests github.PullRequestClient
+	Comments     github.Commenter
+	Members      github.OrganizationMemberChecker
+}
+
+type Options struct {
+	// Secret is the HMAC secret shared with Prow's hook.
+	Secret []byte
+	// TargetOrganizations are the organizations whose repositories may be
+	// synchronized.
+	TargetOrganizations []string
+	// TrustedUsers may use the command in addition to the members of the
+	// repository's organization.
+	TrustedUsers []string
+	// AllowSHA1 accepts the SHA1-based signature sent by older versions of
+	// Prow's hook.
+	AllowSHA1 bool
+}
+
+type Server struct {
+	log     logrus.FieldLogger
+	opts    Options
+	clients Clients
+	runner  Runner
+
+	// synchronizations must not run concurrently
+	runLock sync.Mutex
+	// wg tracks in-flight synchronizations
+	wg sync.WaitGroup
+}
+
+var _ http.Handler = &Server{}
+
+func NewServer(log logrus.FieldLogger, opts Options, clients Clients, runner Runner) *Server {
+	return &Server{
+		log:     log,
+		opts:    opts,
+		clients: clients,
+		runner:  runner,
+	}
+}
+
+// Wait blocks until all in-flight synchronizations are completed.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Help returns the plugin help in the format Prow's hook expects.
+func (s *Server) Help() PluginHelp {
+	return PluginHelp{
+		Description: fmt.Sprintf("The %s plugin synchronizes the %s file with the GitHub teams.", Name, prow.OwnersAliasesFilename),
+		Config: map[string]string{
+			"": fmt.Sprintf("Enabled for repositories in: %s", strings.Join(s.opts.TargetOrganizations, ", ")),
+		},
+		Events: []string{"issue_comment"},
+		Commands: []PluginCommand{{
+			Usage:       Command,
+			Featured:    false,
+			Description: fmt.Sprintf("Synchronizes the %s file of the pull request's base branch (or the default branch for issues) and creates a pull request if it is out of date.", prow.OwnersAliasesFilename),
+			Examples:    []string{Command},
+			WhoCanUse:   "Members of the organization and trusted users",
+		}},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/help" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Help())
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, webhook.MaxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if !webhook.ValidateRequest(s.opts.Secret, r.Header, payload, s.opts.AllowSHA1) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	eventType := r.Header.Get("X-GitHub-Event")
+	if eventType != "issue_comment" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var event issueCommentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if event.Action != "created" || !commandRegexp.MatchString(event.Comment.Body) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// hook does not wait for plugins to finish, so do the work in the background
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.handleCommand(context.Background(), event)
+	}()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleCommand(ctx context.Context, event issueCommentEvent) {
+	org := event.Repository.Owner.Login
+	repo := event.Repository.Name
+
+	log := s.log.WithFields(logrus.Fields{
+		"repo":   fmt.Sprintf("%s/%s", org, repo),
+		"number": event.Issue.Number,
+		"user":   event.Comment.User.Login,
+	})
+
+	if !contains(s.opts.TargetOrganizations, org) {
+		log.Debug("Ignoring command in unmanaged organization.")
+		s.reply(log, event, fmt.Sprintf("This repository is not managed by %s.", Name))
+		return
+	}
+
+	trusted, err := s.isTrusted(org, event.Comment.User.Login)
+	if err != nil {
+		log.WithError(err).Warn("Failed to check organization membership.")
+		s.reply(log, event, "Failed to check whether you are allowed to use this command.")
+		return
+	}
+
+	if !trusted {
+		log.Info("Ignoring command from untrusted user.")
+		s.reply(log, event, fmt.Sprintf("Only members of the `%s` organization can use `%s`.", org, Command))
+		return
+	}
+
+	branch := event.Repository.DefaultBranch
+	if event.Issue.PullRequest != nil {
+		base, err := s.clients.PullRequests.GetPullRequestBaseBranch(org, repo, event.Issue.Number)
+		if err != nil {
+			log.WithError(err).Warn("Failed to determine base branch.")
+			s.reply(log, event, "Failed to determine the base branch of this pull request.")
+			return
+		}
+
+		branch = base
+	}
+
+	log = log.WithField("branch", branch)
+	log.Info("Synchronization requested.")
+
+	s.runLock.Lock()
+	result, err := s.runner.RunScoped(ctx, syncer.Scope{
+		Organization: org,
+		Repository:   repo,
+		Branch:       branch,
+		// the user asked for it, so do not wait for activity on the branch
+		SkipActivityCheck: true,
+	})
+	s.runLock.Unlock()
+
+	if err != nil {
+		log.WithError(err).Error("Synchronization failed.")
+		s.reply(log, event, fmt.Sprintf("Failed to synchronize the `%s` file on `%s`: %v", prow.OwnersAliasesFilename, branch, err))
+		return
+	}
+
+	s.reply(log, event, describeResult(result, branch))
+}
+
+func (s *Server) isTrusted(org, login string) (bool, error) {
+	if contains(s.opts.TrustedUsers, login) {
+		return true, nil
+	}
+
+	return s.clients.Members.IsOrganizationMember(org, login)
+}
+
+func (s *Server) reply(log logrus.FieldLogger, event issueCommentEvent, message string) {
+	body := fmt.Sprintf("@%s: %s", event.Comment.User.Login, message)
+
+	if err := s.clients.Comments.CreateComment(githubv4.ID(event.Issue.NodeID), body); err != nil {
+		log.WithError(err).Warn("Failed to create comment.")
+	}
+}
+
+func describeResult(result *syncer.Result, branch string) string {
+	if result == nil || len(result.Branches) == 0 {
+		return fmt.Sprintf("Branch `%s` does not exist.", branch)
+	}
+
+	event := result.Branches[0]
+	filename := fmt.Sprintf("`%s`", prow.OwnersAliasesFilename)
+
+	switch event.Decision {
+	case syncer.DecisionIgnored:
+		return fmt.Sprintf("Branch `%s` is not selected for synchronization.", branch)
+	case syncer.DecisionNoAliasesFile:
+		return fmt.Sprintf("There is no %s file on `%s`.", filename, branch)
+	case syncer.DecisionOptedOut:
+		return fmt.Sprintf("The %s file on `%s` was not synchronized: %s.", filename, branch, event.Reason)
+	case syncer.DecisionInvalidAliasesFile:
+		return fmt.Sprintf("The %s file on `%s` is invalid: %v", filename, branch, event.Error)
+	case syncer.DecisionUpToDate:
+		return fmt.Sprintf("The %s file on `%s` is already up-to-date.", filename, branch)
+	case syncer.DecisionPullRequestExists:
+		return fmt.Sprintf("The %s file on `%s` is out of date, it will be updated by #%d.", filename, branch, event.PullRequest)
+	case syncer.DecisionPullRequestCreated:
+		return fmt.Sprintf("The %s file on `%s` was out of date, I created #%d to update it.", filename, branch, event.PullRequest)
+	case syncer.DecisionUpdated:
+		return fmt.Sprintf("The %s file on `%s` was out of date and has been updated.", filename, branch)
+	case syncer.DecisionDryRun:
+		return fmt.Sprintf("The %s file on `%s` is out of date, but I am running in dry-run mode.", filename, branch)
+	case syncer.DecisionFailed:
+		return fmt.Sprintf("Failed to update the %s file on `%s`: %v", filename, branch, event.Error)
+	default:
+		return fmt.Sprintf("The %s file on `%s` was not synchronized (%s).", filename, branch, event.Decision)
+	}
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if strings.EqualFold(s, needle) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+	"go.xrstf.de/prow-aliases-syncer/pkg/webhook"
+)
+
+var testSecret = []byte("s3cr3t")
+
+const commentPayload = `{
+	"action": "created",
+	"issue": {"number": 1, "node_id": "PR_1", "pull_request": {"url": "https://api.github.com/repos/org/repo/pulls/1"}},
+	"comment": {"body": "please\n/sync-aliases\n", "user": {"login": "alice"}},
+	"repository": {"name": "repo", "default_branch": "main", "owner": {"login": "org"}}
+}`
+
+// signSHA1 signs the payload the way Prow's hook does for external plugins.
+func signSHA1(payload []byte) string {
+	mac := hmac.New(sha1.New, testSecret)
+	mac.Write(payload)
+
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newTestServer returns a plugin server for a repository whose release-1.0
+// branch is outdated and stale.
+func newTestServer(t *testing.T, opts Options) (*Server, *fake.GitHub) {
+	gh := fake.NewGitHub()
+	gh.Teams["org"] = []github.Team{
+		{Slug: "sig-a", Members: []string{"alice", "bob"}},
+	}
+	gh.Members["org"] = []string{"alice"}
+	gh.Repositories["org"] = []github.Repository{{
+		ID:   "repo-id",
+		Name: "repo",
+		Branches: []github.Branch{
+			// stale, but explicitly requested
+			{Name: "release-1.0", MostRecentCommit: time.Now().Add(-1000 * time.Hour), Aliases: "aliases:\n  sig-a: [alice]\n"},
+		},
+	}}
+	gh.PullRequests = []fake.PullRequest{{
+		Org:    "org",
+		Repo:   "repo",
+		Number: 1,
+		Base:   "release-1.0",
+		Head:   "feature",
+	}}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote("org", "repo", map[string]fake.Files{
+		"release-1.0": {prow.OwnersAliasesFilename: "aliases:\n  sig-a: [alice]\n"},
+	})
+
+	s, err := syncer.New(fake.Logger(), syncer.Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+	}, syncer.Options{
+		Organizations: []string{"org"},
+		Branches:      []string{"release-*"},
+		MaxAge:        time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+
+	opts.Secret = testSecret
+	opts.TargetOrganizations = []string{"org"}
+
+	server := NewServer(fake.Logger(), opts, Clients{
+		PullRequests: gh,
+		Comments:     gh,
+		Members:      gh,
+	}, s)
+
+	return server, gh
+}
+
+// sendComment delivers the comment event and waits for the command to be
+// handled.
+func sendComment(t *testing.T, server *Server, payload string, signatureHeader string, signature string) int {
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	req, err := http.NewRequest(http.MethodPost, httpServer.URL, bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	req.Header.Set("X-GitHub-Event", "issue_comment")
+	req.Header.Set(signatureHeader, signature)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to deliver event: %v", err)
+	}
+	resp.Body.Close()
+
+	server.Wait()
+
+	return resp.StatusCode
+}
+
+func TestSyncAliasesCommand(t *testing.T) {
+	server, gh := newTestServer(t, Options{AllowSHA1: true})
+
+	if status := sendComment(t, server, commentPayload, "X-Hub-Signature", signSHA1([]byte(commentPayload))); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d.", status)
+	}
+
+	if len(gh.PullRequests) != 2 {
+		t.Fatalf("Expected a new pull request, got %+v.", gh.PullRequests)
+	}
+
+	if pr := gh.PullRequests[1]; pr.Base != "release-1.0" || pr.Head != "update-release-1.0-owners" {
+		t.Errorf("Unexpected pull request: %+v", pr)
+	}
+
+	comments := gh.Comments["PR_1"]
+	if len(comments) != 1 || !strings.HasPrefix(comments[0], "@alice: ") || !strings.Contains(comments[0], "#2") {
+		t.Errorf("Unexpected comments: %v", comments)
+	}
+}
+
+func TestSyncAliasesCommandRequiresTrustedUser(t *testing.T) {
+	payload := strings.Replace(commentPayload, `"login": "alice"`, `"login": "mallory"`, 1)
+
+	testcases := []struct {
+		name         string
+		trustedUsers []string
+		expectedPRs  int
+	}{
+		{
+			name:        "outsiders are rejected",
+			expectedPRs: 1,
+		},
+		{
+			name:         "trusted users are accepted",
+			trustedUsers: []string{"Mallory"},
+			expectedPRs:  2,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			server, gh := newTestServer(t, Options{TrustedUsers: testcase.trustedUsers})
+
+			if status := sendComment(t, server, payload, "X-Hub-Signature-256", webhook.Sign(testSecret, []byte(payload))); status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d.", status)
+			}
+
+			if len(gh.PullRequests) != testcase.expectedPRs {
+				t.Errorf("Expected %d pull requests, got %+v.", testcase.expectedPRs, gh.PullRequests)
+			}
+
+			if comments := gh.Comments["PR_1"]; len(comments) != 1 || !strings.HasPrefix(comments[0], "@mallory: ") {
+				t.Errorf("Unexpected comments: %v", comments)
+			}
+		})
+	}
+}
+
+func TestSyncAliasesCommandRespectsBranchSelector(t *testing.T) {
+	payload := strings.Replace(commentPayload, `"pull_request": {"url": "https://api.github.com/repos/org/repo/pulls/1"}`, `"pull_request": null`, 1)
+
+	server, gh := newTestServer(t, Options{})
+
+	// the default branch is outdated, but not selected by "release-*"
+	repo := &gh.Repositories["org"][0]
+	repo.Branches = append(repo.Branches, github.Branch{Name: "main", MostRecentCommit: time.Now(), Aliases: "aliases:\n  sig-a: [alice]\n"})
+
+	if status := sendComment(t, server, payload, "X-Hub-Signature-256", webhook.Sign(testSecret, []byte(payload))); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d.", status)
+	}
+
+	if len(gh.PullRequests) != 1 {
+		t.Errorf("Expected no new pull request, got %+v.", gh.PullRequests)
+	}
+
+	if comments := gh.Comments["PR_1"]; len(comments) != 1 || !strings.Contains(comments[0], "not selected") {
+		t.Errorf("Unexpected comments: %v", comments)
+	}
+}
+
+func TestSHA1SignatureIsOptIn(t *testing.T) {
+	server, gh := newTestServer(t, Options{})
+
+	if status := sendComment(t, server, commentPayload, "X-Hub-Signature", signSHA1([]byte(commentPayload))); status != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d.", status)
+	}
+
+	if len(gh.Comments) != 0 {
+		t.Errorf("Expected no comments, got %v.", gh.Comments)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	server := NewServer(fake.Logger(), Options{TargetOrganizations: []string{"org"}}, Clients{}, nil)
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/help")
+	if err != nil {
+		t.Fatalf("Failed to get help: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var help PluginHelp
+	if err := json.NewDecoder(resp.Body).Decode(&help); err != nil {
+		t.Fatalf("Failed to decode help: %v", err)
+	}
+
+	if len(help.Commands) != 1 || help.Commands[0].Usage != Command {
+		t.Errorf("Unexpected help: %+v", help)
+	}
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package plugin
+
+// PluginHelp mirrors Prow's pluginhelp.PluginHelp, which is served on /help
+// and displayed on Deck's plugin help page.
+type PluginHelp struct {
+	Description string
+	Config      map[string]string
+	Events      []string
+	Commands    []PluginCommand
+}
+
+// PluginCommand mirrors Prow's pluginhelp.Command.
+type PluginCommand struct {
+	Usage       string
+	Featured    bool
+	Description string
+	Examples    []string
+	WhoCanUse   string
+}
+
+// issueCommentEvent contains the relevant fields of GitHub's issue_comment
+// webhook, which Prow's hook forwards unchanged.
+type issueCommentEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int       `json:"number"`
+		NodeID      string    `json:"node_id"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"comment"`
+	Repository struct {
+		Name          string `json:"name"`
+		DefaultBranch string `json:"default_branch"`
+		Owner         struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
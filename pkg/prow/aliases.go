@@ -19,15 +19,29 @@ const (
 
 type OwnersAliases struct {
 	Aliases map[string][]string
+
+	// MemberComments are written as line comments after members, by alias
+	// and member.
+	MemberComments map[string]map[string]string `yaml:"-"`
+	// AliasComments are written as comments above aliases, by alias.
+	AliasComments map[string]string `yaml:"-"`
 }
 
 func FromString(data string) (*OwnersAliases, error) {
-	result := &OwnersAliases{}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
 
-	if err := yaml.Unmarshal([]byte(data), result); err != nil {
+	result := &OwnersAliases{}
+	if err := doc.Decode(result); err != nil {
 		return nil, err
 	}
 
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		result.parseComments(doc.Content[0])
+	}
+
 	return result, nil
 }
 
@@ -46,7 +60,14 @@ func (oa *OwnersAliases) ToYAML(header string) (string, error) {
 	encoder := yaml.NewEncoder(&buf)
 	encoder.SetIndent(2)
 
-	if err := encoder.Encode(oa); err != nil {
+	var node yaml.Node
+	if err := node.Encode(oa); err != nil {
+		return "", err
+	}
+
+	oa.addComments(&node)
+
+	if err := encoder.Encode(&node); err != nil {
 		return "", nil
 	}
 
@@ -57,6 +78,82 @@ func (oa *OwnersAliases) ToYAML(header string) (string, error) {
 	return fmt.Sprintf("%s\n\n%s", strings.TrimSpace(header), buf.String()), nil
 }
 
+// addComments decorates the encoded aliases with the comments.
+func (oa *OwnersAliases) addComments(doc *yaml.Node) {
+	if len(oa.MemberComments) == 0 && len(oa.AliasComments) == 0 {
+		return
+	}
+
+	// doc is a mapping with a single "aliases" key
+	if doc.Kind != yaml.MappingNode || len(doc.Content) != 2 {
+		return
+	}
+
+	aliases := doc.Content[1]
+
+	for i := 0; i+1 < len(aliases.Content); i += 2 {
+		key, members := aliases.Content[i], aliases.Content[i+1]
+
+		if comment := oa.AliasComments[key.Value]; comment != "" {
+			key.HeadComment = comment
+		}
+
+		for _, member := range members.Content {
+			if comment := oa.MemberComments[key.Value][member.Value]; comment != "" {
+				member.LineComment = comment
+			}
+		}
+	}
+}
+
+// parseComments is the opposite of addComments.
+func (oa *OwnersAliases) parseComments(doc *yaml.Node) {
+	if doc.Kind != yaml.MappingNode || len(doc.Content) != 2 {
+		return
+	}
+
+	aliases := doc.Content[1]
+
+	for i := 0; i+1 < len(aliases.Content); i += 2 {
+		key, members := aliases.Content[i], aliases.Content[i+1]
+
+		if comment := stripComment(key.HeadComment); comment != "" {
+			if oa.AliasComments == nil {
+				oa.AliasComments = map[string]string{}
+			}
+
+			oa.AliasComments[key.Value] = comment
+		}
+
+		for _, member := range members.Content {
+			if comment := stripComment(member.LineComment); comment != "" {
+				if oa.MemberComments == nil {
+					oa.MemberComments = map[string]map[string]string{}
+				}
+
+				if oa.MemberComments[key.Value] == nil {
+					oa.MemberComments[key.Value] = map[string]string{}
+				}
+
+				oa.MemberComments[key.Value][member.Value] = comment
+			}
+		}
+	}
+}
+
+// stripComment removes the "#" from each line of a comment.
+func stripComment(comment string) string {
+	lines := []string{}
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (os *OwnersAliases) Sort() {
 	for team, members := range os.Aliases {
 		sort.Strings(members)
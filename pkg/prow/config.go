@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package prow
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the subset of Prow's config.yaml that describes which branches
+// Prow cares about. All other fields are ignored.
+type Config struct {
+	Tide struct {
+		Queries []TideQuery `yaml:"queries"`
+	} `yaml:"tide"`
+	BranchProtection BranchProtection `yaml:"branch-protection"`
+}
+
+type TideQuery struct {
+	Orgs             []string `yaml:"orgs"`
+	Repos            []string `yaml:"repos"`
+	ExcludedRepos    []string `yaml:"excludedRepos"`
+	IncludedBranches []string `yaml:"includedBranches"`
+	ExcludedBranches []string `yaml:"excludedBranches"`
+}
+
+// BranchProtection mirrors Prow's branch-protection section. Each level
+// inherits the policy of its parent: protect is overridden by the child,
+// exclude and include patterns are combined.
+type BranchProtection struct {
+	Policy `yaml:",inline"`
+	Orgs   map[string]OrgProtection `yaml:"orgs"`
+}
+
+type OrgProtection struct {
+	Policy `yaml:",inline"`
+	Repos  map[string]RepoProtection `yaml:"repos"`
+}
+
+type RepoProtection struct {
+	Policy   `yaml:",inline"`
+	Branches map[string]Policy `yaml:"branches"`
+}
+
+// Policy is the subset of a Prow protection policy that determines whether
+// a branch is protected.
+type Policy struct {
+	Protect *bool `yaml:"protect"`
+	// Exclude and Include are regular expressions for branch names.
+	Exclude []string `yaml:"exclude"`
+	Include []string `yaml:"include"`
+}
+
+func (p Policy) apply(child Policy) Policy {
+	result := Policy{
+		Protect: p.Protect,
+		Exclude: append(slices.Clone(p.Exclude), child.Exclude...),
+		Include: append(slices.Clone(p.Include), child.Include...),
+	}
+
+	if child.Protect != nil {
+		result.Protect = child.Protect
+	}
+
+	return result
+}
+
+func (p Policy) validate() error {
+	for _, pattern := range append(slices.Clone(p.Exclude), p.Include...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func (p Policy) protects(branch string) bool {
+	if p.Protect == nil || !*p.Protect {
+		return false
+	}
+
+	for _, pattern := range p.Exclude {
+		if matched, _ := regexp.MatchString(pattern, branch); matched {
+			return false
+		}
+	}
+
+	if len(p.Include) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Include {
+		if matched, _ := regexp.MatchString(pattern, branch); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func LoadConfig(filename string) (*Config, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("invalid Prow config: %w", err)
+	}
+
+	if err := config.BranchProtection.validate(); err != nil {
+		return nil, fmt.Errorf("invalid Prow config: %w", err)
+	}
+
+	return config, nil
+}
+
+// MatchBranch returns the section of the config that lists the branch
+// ("tide" or "branch-protection"), or an empty string.
+func (c *Config) MatchBranch(org, repo, branch string) string {
+	for _, q := range c.Tide.Queries {
+		if q.matches(org, repo, branch) {
+			return "tide"
+		}
+	}
+
+	if c.BranchProtection.policy(org, repo, branch).protects(branch) {
+		return "branch-protection"
+	}
+
+	return ""
+}
+
+// policy returns the effective policy for the branch, as Prow's
+// branchprotector would apply it.
+func (b BranchProtection) policy(org, repo, branch string) Policy {
+	orgProtection := b.Orgs[org]
+	repoProtection := orgProtection.Repos[repo]
+
+	return b.Policy.
+		apply(orgProtection.Policy).
+		apply(repoProtection.Policy).
+		apply(repoProtection.Branches[branch])
+}
+
+func (b BranchProtection) validate() error {
+	if err := b.Policy.validate(); err != nil {
+		return err
+	}
+
+	for orgName, org := range b.Orgs {
+		if err := org.Policy.validate(); err != nil {
+			return fmt.Errorf("%s: %w", orgName, err)
+		}
+
+		for repoName, repo := range org.Repos {
+			if err := repo.Policy.validate(); err != nil {
+				return fmt.Errorf("%s/%s: %w", orgName, repoName, err)
+			}
+
+			for branchName, branch := range repo.Branches {
+				if err := branch.validate(); err != nil {
+					return fmt.Errorf("%s/%s@%s: %w", orgName, repoName, branchName, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (q TideQuery) matches(org, repo, branch string) bool {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+
+	inOrg := slices.Contains(q.Orgs, org) && !slices.Contains(q.ExcludedRepos, fullName)
+	if !inOrg && !slices.Contains(q.Repos, fullName) {
+		return false
+	}
+
+	if len(q.IncludedBranches) > 0 && !slices.Contains(q.IncludedBranches, branch) {
+		return false
+	}
+
+	return !slices.Contains(q.ExcludedBranches, branch)
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package prow
+
+import (
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testConfig = `
+plank:
+  job_url_prefix_config: {}
+tide:
+  queries:
+    - orgs: [myorg]
+      excludedRepos: [myorg/legacy]
+      includedBranches: [main, release-1.0]
+    - repos: [myorg/legacy]
+      excludedBranches: [gh-pages]
+branch-protection:
+  exclude: ["^dependabot/"]
+  orgs:
+    myorg:
+      repos:
+        other:
+          branches:
+            develop:
+              protect: true
+            listed:
+              required_linear_history: true
+        protected:
+          protect: true
+          exclude: ["^feature-"]
+          branches:
+            unprotected:
+              protect: false
+    protectedorg:
+      protect: true
+      include: ["^main$", "^release-"]
+      repos:
+        optout:
+          protect: false
+`
+
+func TestConfigMatchBranch(t *testing.T) {
+	config := &Config{}
+	if err := yaml.Unmarshal([]byte(testConfig), config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	testcases := []struct {
+		org      string
+		repo     string
+		branch   string
+		expected string
+	}{
+		{org: "myorg", repo: "repo", branch: "main", expected: "tide"},
+		{org: "myorg", repo: "repo", branch: "release-1.0", expected: "tide"},
+		{org: "myorg", repo: "repo", branch: "feature", expected: ""},
+		{org: "myorg", repo: "legacy", branch: "feature", expected: "tide"},
+		{org: "myorg", repo: "legacy", branch: "gh-pages", expected: ""},
+		{org: "myorg", repo: "other", branch: "develop", expected: "branch-protection"},
+		{org: "otherorg", repo: "repo", branch: "main", expected: ""},
+		{org: "myorg", repo: "other", branch: "listed", expected: ""},
+		{org: "myorg", repo: "protected", branch: "develop", expected: "branch-protection"},
+		{org: "myorg", repo: "protected", branch: "feature-x", expected: ""},
+		{org: "myorg", repo: "protected", branch: "dependabot/foo", expected: ""},
+		{org: "myorg", repo: "protected", branch: "unprotected", expected: ""},
+		{org: "protectedorg", repo: "repo", branch: "main", expected: "branch-protection"},
+		{org: "protectedorg", repo: "repo", branch: "release-1.0", expected: "branch-protection"},
+		{org: "protectedorg", repo: "repo", branch: "feature", expected: ""},
+		{org: "protectedorg", repo: "optout", branch: "main", expected: ""},
+	}
+
+	for i, tt := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			if matched := config.MatchBranch(tt.org, tt.repo, tt.branch); matched != tt.expected {
+				t.Fatalf("Expected MatchBranch(%q, %q, %q) to return %q, got %q.", tt.org, tt.repo, tt.branch, tt.expected, matched)
+			}
+		})
+	}
+}
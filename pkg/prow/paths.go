@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package prow
+
+import (
+	"path"
+	"strings"
+)
+
+// IsPathPattern returns true if the given aliases path contains any glob
+// characters.
+func IsPathPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// MatchPath matches a slash-separated file path against a glob pattern. In
+// addition to the syntax of path.Match, a "**" segment matches any number
+// of directories, e.g. "**/OWNERS_ALIASES" matches the file in the root
+// directory and all subdirectories.
+func MatchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package prow
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	testcases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "OWNERS_ALIASES", name: "OWNERS_ALIASES", expected: true},
+		{pattern: "OWNERS_ALIASES", name: "docs/OWNERS_ALIASES", expected: false},
+		{pattern: "*/OWNERS_ALIASES", name: "docs/OWNERS_ALIASES", expected: true},
+		{pattern: "*/OWNERS_ALIASES", name: "OWNERS_ALIASES", expected: false},
+		{pattern: "*/OWNERS_ALIASES", name: "a/b/OWNERS_ALIASES", expected: false},
+		{pattern: "**/OWNERS_ALIASES", name: "OWNERS_ALIASES", expected: true},
+		{pattern: "**/OWNERS_ALIASES", name: "a/b/OWNERS_ALIASES", expected: true},
+		{pattern: "**/OWNERS_ALIASES", name: "a/b/OWNERS", expected: false},
+		{pattern: "staging/**/OWNERS_ALIASES", name: "staging/x/OWNERS_ALIASES", expected: true},
+		{pattern: "staging/**/OWNERS_ALIASES", name: "vendor/x/OWNERS_ALIASES", expected: false},
+		{pattern: "staging/**", name: "staging/x/OWNERS_ALIASES", expected: true},
+		{pattern: "charts/*/OWNERS_ALIASES", name: "charts/foo/OWNERS_ALIASES", expected: true},
+	}
+
+	for i, tt := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			if matched := MatchPath(tt.pattern, tt.name); matched != tt.expected {
+				t.Fatalf("Expected MatchPath(%q, %q) to return %v.", tt.pattern, tt.name, tt.expected)
+			}
+		})
+	}
+}
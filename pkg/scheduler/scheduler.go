@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package scheduler runs synchronizations periodically and reports their
+// status via HTTP.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+// RunFunc performs a single synchronization.
+type RunFunc func(ctx context.Context) (*syncer.Result, error)
+
+type Scheduler struct {
+	log      logrus.FieldLogger
+	interval time.Duration
+	jitter   float64
+	run      RunFunc
+
+	lock   sync.RWMutex
+	status Status
+}
+
+// New creates a scheduler that runs every interval, plus/minus a random
+// jitter (given as a fraction of the interval, e.g. 0.1 for ±10%).
+func New(log logrus.FieldLogger, interval time.Duration, jitter float64, run RunFunc) *Scheduler {
+	return &Scheduler{
+		log:      log,
+		interval: interval,
+		jitter:   jitter,
+		run:      run,
+	}
+}
+
+// Start runs synchronizations until the context is cancelled. A run that is
+// in progress when the context is cancelled is completed first.
+func (s *Scheduler) Start(ctx context.Context) {
+	for {
+		s.runOnce(ctx)
+
+		wait := s.nextDelay()
+		next := time.Now().Add(wait)
+
+		s.lock.Lock()
+		s.status.NextRun = &next
+		s.lock.Unlock()
+
+		s.log.WithField("next", next.Format(time.RFC3339)).Info("Waiting for next synchronization…")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (s *Scheduler) nextDelay() time.Duration {
+	if s.jitter <= 0 {
+		return s.interval
+	}
+
+	// random value in [-jitter, +jitter)
+	factor := (rand.Float64()*2 - 1) * s.jitter
+
+	return s.interval + time.Duration(factor*float64(s.interval))
+}
+
+func (s *Scheduler) runOnce(ctx context.Context) {
+	started := time.Now()
+
+	s.lock.Lock()
+	s.status.Running = true
+	s.status.NextRun = nil
+	s.lock.Unlock()
+
+	result, err := s.safeRun(ctx)
+
+	run := RunStatus{
+		Started:  started,
+		Finished: time.Now(),
+		Success:  err == nil && (result == nil || !result.Failed()),
+	}
+
+	if err != nil {
+		run.Error = err.Error()
+		s.log.WithError(err).Error("Synchronization failed.")
+	}
+
+	if result != nil {
+		for _, b := range result.Branches {
+			run.Branches = append(run.Branches, newBranchStatus(b))
+		}
+	}
+
+	s.lock.Lock()
+	s.status.Running = false
+	s.status.Runs++
+	if !run.Success {
+		s.status.FailedRuns++
+	}
+	s.status.LastRun = &run
+	s.lock.Unlock()
+}
+
+// safeRun makes sure that a panicking synchronization does not take down
+// the whole process.
+func (s *Scheduler) safeRun(ctx context.Context) (result *syncer.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return s.run(ctx)
+}
+
+// Status returns a copy of the current status.
+func (s *Scheduler) Status() Status {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.status
+}
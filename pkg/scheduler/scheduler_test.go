@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+func TestSchedulerSurvivesFailures(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	run := func(ctx context.Context) (*syncer.Result, error) {
+		runs++
+
+		switch runs {
+		case 1:
+			return nil, errors.New("boom")
+		case 2:
+			panic("oh no")
+		default:
+			cancel()
+
+			return &syncer.Result{
+				Branches: []syncer.Event{{
+					Organization: "org",
+					Repository:   "repo",
+					Branch:       "main",
+					Decision:     syncer.DecisionPullRequestCreated,
+					PullRequest:  42,
+				}},
+			}, nil
+		}
+	}
+
+	s := New(log, time.Millisecond, 0.5, run)
+
+	done := make(chan struct{})
+	go func() {
+		s.Start(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scheduler did not stop.")
+	}
+
+	status := s.Status()
+	if status.Runs != 3 || status.FailedRuns != 2 {
+		t.Fatalf("Expected 3 runs with 2 failures, got %d runs and %d failures.", status.Runs, status.FailedRuns)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/status", nil))
+
+	var reported Status
+	if err := json.NewDecoder(recorder.Body).Decode(&reported); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+
+	if reported.LastRun == nil || !reported.LastRun.Success || len(reported.LastRun.Branches) != 1 {
+		t.Fatalf("Unexpected last run: %+v", reported.LastRun)
+	}
+
+	if pr := reported.LastRun.Branches[0].PullRequest; pr != 42 {
+		t.Errorf("Expected PR 42, got %d.", pr)
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+type Status struct {
+	Running    bool       `json:"running"`
+	Runs       int        `json:"runs"`
+	FailedRuns int        `json:"failedRuns"`
+	NextRun    *time.Time `json:"nextRun,omitempty"`
+	LastRun    *RunStatus `json:"lastRun,omitempty"`
+}
+
+type RunStatus struct {
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Success  bool           `json:"success"`
+	Error    string         `json:"error,omitempty"`
+	Branches []BranchStatus `json:"branches,omitempty"`
+}
+
+type BranchStatus struct {
+	Organization string          `json:"organization"`
+	Repository   string          `json:"repository"`
+	Branch       string          `json:"branch"`
+	Decision     syncer.Decision `json:"decision"`
+	Selector     string          `json:"selector,omitempty"`
+	Reason       string          `json:"reason,omitempty"`
+	Fallback     bool            `json:"fallback,omitempty"`
+	PullRequest  int             `json:"pullRequest,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+func newBranchStatus(e syncer.Event) BranchStatus {
+	status := BranchStatus{
+		Organization: e.Organization,
+		Repository:   e.Repository,
+		Branch:       e.Branch,
+		Decision:     e.Decision,
+		Selector:     e.Selector,
+		Reason:       e.Reason,
+		Fallback:     e.Fallback,
+		PullRequest:  e.PullRequest,
+	}
+
+	if e.Error != nil {
+		status.Error = e.Error.Error()
+	}
+
+	return status
+}
+
+// Handler returns a HTTP handler serving /healthz and /status.
+func (s *Scheduler) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(s.Status())
+	})
+
+	return mux
+}
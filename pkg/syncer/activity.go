@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"fmt"
+	"time"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+// ActivitySignal is a source of activity on a branch besides its commits.
+type ActivitySignal string
+
+const (
+	// ActivityLatestTag considers the most recent tag in the branch.
+	ActivityLatestTag ActivitySignal = "latest-tag"
+	// ActivityMergedPullRequest considers the most recently merged pull
+	// request into the branch.
+	ActivityMergedPullRequest ActivitySignal = "merged-pr"
+)
+
+var AllActivitySignals = []ActivitySignal{ActivityLatestTag, ActivityMergedPullRequest}
+
+func activityOptions(opts Options) github.ActivityOptions {
+	return github.ActivityOptions{
+		IgnoredUsers:      opts.IgnoredUsers,
+		IgnoredCommitters: opts.IgnoredCommitters,
+		IgnoredEmails:     opts.IgnoredEmails,
+		IgnoredMessages:   opts.IgnoredMessages,
+		PeekDepth:         opts.PeekDepth,
+	}
+}
+
+// recentActivity checks the ActivitySignals for a branch whose most recent
+// commit is too old. It returns the first signal that shows recent activity
+// or an empty string.
+func (s *Syncer) recentActivity(opts Options, org string, repo string, branch github.Branch) (ActivitySignal, error) {
+	since := time.Now().Add(-opts.MaxAge)
+
+	for _, signal := range opts.ActivitySignals {
+		var (
+			latest time.Time
+			err    error
+		)
+
+		switch signal {
+		case ActivityLatestTag:
+			latest, err = s.clients.Activity.GetLatestTag(org, repo, branch.Name, since)
+		case ActivityMergedPullRequest:
+			latest, err = s.clients.Activity.GetLastMergedPullRequest(org, repo, branch.Name)
+		default:
+			err = fmt.Errorf("unknown activity signal %q", signal)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to check %s: %w", signal, err)
+		}
+
+		if latest.After(since) {
+			return signal, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+func TestActivitySignals(t *testing.T) {
+	stale := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	gh := fake.NewGitHub()
+	gh.MergedPullRequests[testOrg+"/repo/release-1.0"] = recent
+	gh.LatestTags[testOrg+"/repo/release-1.1"] = recent
+	gh.LatestTags[testOrg+"/repo/main"] = stale
+
+	testcases := []struct {
+		name     string
+		signals  []ActivitySignal
+		expected []Decision
+	}{
+		{
+			name:     "no signals",
+			signals:  nil,
+			expected: []Decision{DecisionStale, DecisionStale, DecisionStale},
+		},
+		{
+			name:     "merged pull requests",
+			signals:  []ActivitySignal{ActivityMergedPullRequest},
+			expected: []Decision{DecisionStale, DecisionOutOfSync, DecisionStale},
+		},
+		{
+			name:     "all signals",
+			signals:  []ActivitySignal{ActivityLatestTag, ActivityMergedPullRequest},
+			expected: []Decision{DecisionStale, DecisionOutOfSync, DecisionOutOfSync},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			var events []Decision
+
+			opt := testOptions()
+			opt.ActivitySignals = testcase.signals
+			opt.OnEvent = func(e Event) {
+				events = append(events, e.Decision)
+			}
+
+			s := newTestSyncer(t, Clients{Activity: gh}, opt)
+
+			repos := []github.Repository{{
+				ID:   "repo-id",
+				Name: "repo",
+				Branches: []github.Branch{
+					{Name: "main", HeadOID: "ghi", MostRecentCommit: stale, Aliases: outdatedAliases},
+					{Name: "release-1.0", HeadOID: "abc", MostRecentCommit: stale, Aliases: outdatedAliases},
+					{Name: "release-1.1", HeadOID: "def", MostRecentCommit: stale, Aliases: outdatedAliases},
+				},
+			}}
+
+			if _, err := s.createJobs(context.Background(), fake.Logger(), s.opts, Scope{}, &Result{}, testOrg, repos, testTeams()); err != nil {
+				t.Fatalf("Failed to create jobs: %v", err)
+			}
+
+			if diff := deep.Equal(events, testcase.expected); diff != nil {
+				t.Fatalf("events not equal: %v", diff)
+			}
+		})
+	}
+}
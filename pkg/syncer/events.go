@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import "time"
+
+// Decision describes what the syncer decided to do with a branch.
+type Decision string
+
+const (
+	// DecisionIgnored means the branch did not match the branch filter.
+	DecisionIgnored Decision = "ignored"
+	// DecisionOptedOut means the repository owners disabled the
+	// synchronization of the branch or its aliases files.
+	DecisionOptedOut Decision = "opted-out"
+	// DecisionStale means the branch had no recent activity.
+	DecisionStale Decision = "stale"
+	// DecisionNoAliasesFile means the branch has no aliases file.
+	DecisionNoAliasesFile Decision = "no-aliases-file"
+	// DecisionInvalidAliasesFile means the aliases file could not be parsed.
+	DecisionInvalidAliasesFile Decision = "invalid-aliases-file"
+	// DecisionUpToDate means the aliases file is already in sync.
+	DecisionUpToDate Decision = "up-to-date"
+	// DecisionOutOfSync means the aliases file needs to be updated. This is
+	// always followed by another decision for the same branch.
+	DecisionOutOfSync Decision = "out-of-sync"
+	// DecisionPullRequestExists means there is already an open pull request.
+	DecisionPullRequestExists Decision = "pull-request-exists"
+	// DecisionPullRequestCreated means a new pull request was created.
+	DecisionPullRequestCreated Decision = "pull-request-created"
+	// DecisionUpdated means the branch was updated directly.
+	DecisionUpdated Decision = "updated"
+	// DecisionDryRun means the changes were committed locally, but not pushed.
+	DecisionDryRun Decision = "dry-run"
+	// DecisionFailed means the branch could not be updated.
+	DecisionFailed Decision = "failed"
+	// DecisionPlanned means the change was recorded in a plan.
+	DecisionPlanned Decision = "planned"
+	// DecisionUnchanged means the branch was skipped because neither its
+	// head nor the relevant teams changed since the previous run.
+	DecisionUnchanged Decision = "unchanged"
+)
+
+// Event is emitted for each decision the syncer makes about a branch.
+type Event struct {
+	Organization string
+	Repository   string
+	Branch       string
+	Decision     Decision
+
+	// Selector describes the rule that selected the branch; it is empty
+	// for DecisionIgnored.
+	Selector string
+
+	// HeadBranch is the branch the changes were pushed to (for pull requests).
+	HeadBranch string
+	// PullRequest is the number of the existing or created pull request.
+	PullRequest int
+	// Error is set for DecisionInvalidAliasesFile and DecisionFailed.
+	Error error
+	// Reason is set for DecisionOptedOut and if Fallback is set.
+	Reason string
+	// Fallback is set if the branch should have been updated directly,
+	// but a pull request was used instead because direct pushes into the
+	// branch are not allowed.
+	Fallback bool
+}
+
+// Phase is a part of a synchronization run.
+type Phase string
+
+const (
+	// PhaseTeams is listing the teams in all source organizations.
+	PhaseTeams Phase = "teams"
+	// PhaseRepositories is listing the repositories of a target organization.
+	PhaseRepositories Phase = "repositories"
+	// PhaseProcessing is comparing and updating the aliases files of a
+	// target organization.
+	PhaseProcessing Phase = "processing"
+)
+
+// Result contains the final decision for every branch the syncer looked at.
+type Result struct {
+	Branches []Event
+	// Repositories is the number of repositories that were scanned, not
+	// including repositories that were skipped because they are unchanged.
+	Repositories int
+	// Pushes is the number of branches that were pushed to GitHub.
+	Pushes int
+}
+
+// Count returns the number of branches with the given decision.
+func (r *Result) Count(decision Decision) int {
+	count := 0
+	for _, b := range r.Branches {
+		if b.Decision == decision {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Fallbacks returns the number of branches that were supposed to be updated
+// directly, but got a pull request instead.
+func (r *Result) Fallbacks() int {
+	count := 0
+	for _, b := range r.Branches {
+		if b.Fallback {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r *Result) Failed() bool {
+	return r.Count(DecisionFailed) > 0
+}
+
+func (s *Syncer) observePhase(phase Phase, started time.Time) {
+	if s.opts.OnPhase != nil {
+		s.opts.OnPhase(phase, time.Since(started))
+	}
+}
+
+func (s *Syncer) emit(result *Result, event Event) {
+	if s.opts.OnEvent != nil {
+		s.opts.OnEvent(event)
+	}
+
+	// only record the final decision per branch
+	if event.Decision != DecisionOutOfSync {
+		result.Branches = append(result.Branches, event)
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func (s *Syncer) createJobs(ctx context.Context, log logrus.FieldLogger, opts Options, scope Scope, result *Result, org string, repos []github.Repository, teams []github.Team) ([]github.Repository, error) {
+	todo := []github.Repository{}
+
+	for _, r := range repos {
+		rlog := log.WithField("repo", r.Name)
+		branchesToUpdate := []github.Branch{}
+
+		for j, b := range r.Branches {
+			if scope.Branch != "" && b.Name != scope.Branch {
+				continue
+			}
+
+			blog := rlog.WithField("branch", b.Name)
+			event := Event{
+				Organization: org,
+				Repository:   r.Name,
+				Branch:       b.Name,
+			}
+
+			decide := func(decision Decision) {
+				event.Decision = decision
+				s.emit(result, event)
+
+				if opts.State != nil {
+					opts.State.record(org, r.Name, b, teams, decision)
+				}
+			}
+
+			// apply branch selector
+			event.Selector = opts.BranchSelector.Select(org, r, b)
+			if event.Selector == "" {
+				blog.Debug("Ignored.")
+				decide(DecisionIgnored)
+				continue
+			}
+
+			blog = blog.WithField("selector", event.Selector)
+
+			// respect the wishes of the repository owners, even for
+			// explicitly requested branches
+			settings, optOut, err := branchSettings(r, b)
+			if err != nil {
+				blog.WithError(err).Warn("Invalid settings.")
+				event.Error = err
+				decide(DecisionFailed)
+				continue
+			}
+
+			if optOut != "" {
+				blog.WithField("reason", optOut).Info("Opted out.")
+				event.Reason = optOut
+				decide(DecisionOptedOut)
+				continue
+			}
+
+			// ignore stale branches, unless other signals show activity
+			if !scope.SkipActivityCheck && time.Since(b.MostRecentCommit) > opts.MaxAge {
+				signal, err := s.recentActivity(opts, org, r.Name, b)
+				if err != nil {
+					blog.WithError(err).Warn("Failed to check branch activity.")
+					event.Error = err
+					decide(DecisionFailed)
+					continue
+				}
+
+				if signal == "" {
+					blog.Debug("No recent activity, ignored.")
+					decide(DecisionStale)
+					continue
+				}
+
+				blog.WithField("signal", signal).Debug("No recent commits, but other recent activity.")
+			}
+
+			files := b.AliasesFiles()
+
+			if discoverFiles(opts) {
+				var err error
+
+				files, err = s.clients.Files.GetAliasesFiles(org, r.Name, b.HeadOID, opts.AliasesPaths)
+				if err != nil {
+					blog.WithError(err).Warn("Failed to list aliases files.")
+					event.Error = err
+					decide(DecisionFailed)
+					continue
+				}
+
+				// remember the files in the listed repositories as well, so
+				// that the old content is available when creating a plan
+				r.Branches[j].Files = files
+				b.Files = files
+			}
+
+			// if the branch has no alias file, ignore it
+			if len(files) == 0 {
+				blog.Debug("Has no aliases file.")
+				decide(DecisionNoAliasesFile)
+				continue
+			}
+
+			perFile, err := fileSettingsFor(blog, files, settings, opts)
+			if err != nil {
+				event.Error = err
+				decide(DecisionInvalidAliasesFile)
+				continue
+			}
+
+			if len(perFile) == 0 {
+				blog.Info("All aliases files opted out.")
+				event.Reason = "all aliases files opted out"
+				decide(DecisionOptedOut)
+				continue
+			}
+
+			// each file is updated on its own, but all of them end up in
+			// the same commit
+			changed, err := updateFiles(blog, files, perFile, teams, opts)
+			if err != nil {
+				event.Error = err
+				decide(DecisionInvalidAliasesFile)
+				continue
+			}
+
+			if len(changed) > 0 {
+				blog.Info("File is not identical.")
+				decide(DecisionOutOfSync)
+
+				// store the new data so we do not have to generate it again later
+				b.Files = changed
+
+				branchesToUpdate = append(branchesToUpdate, b)
+			} else {
+				blog.Debug("No changes detected.")
+				decide(DecisionUpToDate)
+			}
+		}
+
+		if len(branchesToUpdate) > 0 {
+			todo = append(todo, github.Repository{
+				ID:            r.ID,
+				Name:          r.Name,
+				DefaultBranch: r.DefaultBranch,
+				Branches:      branchesToUpdate,
+			})
+		}
+	}
+
+	return todo, nil
+}
+
+// fileSettingsFor determines the settings for each aliases file. Files
+// that opted out of the synchronization are not included in the result.
+func fileSettingsFor(log logrus.FieldLogger, files map[string]string, settings *Settings, opts Options) (map[string]fileSettings, error) {
+	result := map[string]fileSettings{}
+
+	for _, path := range sets.List(sets.KeySet(files)) {
+		fs, err := settings.forFile(opts, files[path])
+		if err != nil {
+			log.WithField("file", path).WithError(err).Warn("Invalid marker comment.")
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if fs.ignore {
+			log.WithField("file", path).Debug("File opted out.")
+			continue
+		}
+
+		result[path] = fs
+	}
+
+	return result, nil
+}
+
+// updateFiles returns the new content of all aliases files that are not
+// identical to what the teams would generate.
+func updateFiles(log logrus.FieldLogger, files map[string]string, settings map[string]fileSettings, teams []github.Team, opts Options) (map[string]string, error) {
+	changed := map[string]string{}
+
+	var overrides map[string]config.Alias
+	if opts.Config != nil {
+		overrides = opts.Config.Aliases
+	}
+
+	for _, path := range sets.List(sets.KeySet(settings)) {
+		flog := log.WithField("file", path)
+		fs := settings[path]
+
+		equal, newAliases, err := util.Equal(files[path], teams, util.EqualOptions{
+			Overrides: overrides,
+			Excluded:  append(slices.Clone(fs.excluded), opts.unresolved...),
+			Strict:    fs.strict,
+			Keep:      fs.keep,
+			Header:    fs.header(opts.Header),
+		})
+		if err != nil {
+			flog.WithError(err).Warn("Invalid aliases file.")
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if !equal {
+			flog.Debug("File is not identical.")
+			changed[path] = newAliases
+		}
+	}
+
+	return changed, nil
+}
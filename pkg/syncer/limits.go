@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"fmt"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/logins"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Limits protect against mass changes, e.g. caused by a token that cannot
+// see the members of any team. The zero value disables all limits.
+type Limits struct {
+	// MaxRemovalPercent is the maximum percentage of members that may be
+	// removed from any alias; 0 disables the limit.
+	MaxRemovalPercent int
+	// MaxWrites is the maximum number of branches that are pushed (directly
+	// or for pull requests) in a single run; 0 disables the limit.
+	MaxWrites int
+	// RefuseEmptyAliases prevents aliases without any members, unless they
+	// were empty before.
+	RefuseEmptyAliases bool
+	// RefuseEmptyTeams aborts the run if no teams were found at all or
+	// none of them has any members, e.g. because the token cannot see them.
+	RefuseEmptyTeams bool
+}
+
+// LimitViolation describes a branch that would exceed a limit.
+type LimitViolation struct {
+	Organization string
+	Repository   string
+	Branch       string
+	// Path is the aliases file, if the violation is about a single file.
+	Path   string
+	Reason string
+}
+
+func (v LimitViolation) String() string {
+	if v.Repository == "" {
+		return v.Reason
+	}
+
+	location := fmt.Sprintf("%s/%s@%s", v.Organization, v.Repository, v.Branch)
+	if v.Path != "" {
+		location += ":" + v.Path
+	}
+
+	return fmt.Sprintf("%s: %s", location, v.Reason)
+}
+
+// LimitError is returned if a run would exceed the Limits. It is returned
+// before any branch is written to.
+type LimitError struct {
+	Violations []LimitViolation
+}
+
+func (e *LimitError) Error() string {
+	if len(e.Violations) == 1 {
+		return fmt.Sprintf("safety limit exceeded: %s", e.Violations[0])
+	}
+
+	return fmt.Sprintf("safety limits exceeded in %d cases", len(e.Violations))
+}
+
+// orgJobs are the out-of-sync branches of a target organization.
+type orgJobs struct {
+	org   string
+	repos []github.Repository
+	todo  []github.Repository
+	// unwritten are the todo branches ("repo@branch") that will not be
+	// written to; only determined if Limits.MaxWrites is set.
+	unwritten sets.Set[string]
+}
+
+// checkTeamLimits returns the reason why the teams violate the limits, or
+// an empty string.
+func checkTeamLimits(limits Limits, teams []github.Team) string {
+	if !limits.RefuseEmptyTeams {
+		return ""
+	}
+
+	if len(teams) == 0 {
+		return "no teams were found"
+	}
+
+	for _, team := range teams {
+		if len(team.Members) > 0 {
+			return ""
+		}
+	}
+
+	return "none of the teams has any members"
+}
+
+// unwrittenBranches returns the todo branches ("repo@branch") that will not
+// be written to, because the run is a dry run or a pull request for the
+// branch is already open. This mirrors processTasks and costs additional API
+// requests, so it is only used for Limits.MaxWrites.
+func (s *Syncer) unwrittenBranches(opts Options, org string, todo []github.Repository) (sets.Set[string], error) {
+	unwritten := sets.New[string]()
+
+	for _, task := range todo {
+		for _, branch := range task.Branches {
+			key := fmt.Sprintf("%s@%s", task.Name, branch.Name)
+
+			if opts.DryRun {
+				unwritten.Insert(key)
+				continue
+			}
+
+			if opts.UpdateDirectly {
+				if s.clients.Protection == nil {
+					continue
+				}
+
+				allowed, _, err := s.clients.Protection.CanPushDirectly(org, task.Name, branch.Name, opts.SignedCommits)
+				if err != nil {
+					return nil, fmt.Errorf("%s/%s@%s: %w", org, task.Name, branch.Name, err)
+				}
+
+				if allowed {
+					continue
+				}
+			}
+
+			headBranch, err := renderTemplate(opts.HeadBranch, templateData(org, task.Name, branch))
+			if err != nil {
+				return nil, fmt.Errorf("failed to render head branch template: %w", err)
+			}
+
+			prNumber, err := s.clients.PullRequests.GetPullRequestForBranch(org, task.Name, branch.Name, headBranch)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s@%s: %w", org, task.Name, branch.Name, err)
+			}
+
+			if prNumber > 0 {
+				unwritten.Insert(key)
+			}
+		}
+	}
+
+	return unwritten, nil
+}
+
+// checkLimits returns all violations of the limits by the given jobs.
+func checkLimits(limits Limits, jobs []orgJobs) []LimitViolation {
+	violations := []LimitViolation{}
+	writes := 0
+
+	for _, j := range jobs {
+		for _, task := range j.todo {
+			for _, branch := range task.Branches {
+				if !j.unwritten.Has(fmt.Sprintf("%s@%s", task.Name, branch.Name)) {
+					writes++
+				}
+
+				files := branch.AliasesFiles()
+				for _, path := range sets.List(sets.KeySet(files)) {
+					oldContent := findAliases(j.repos, task.Name, branch.Name, path)
+
+					for _, reason := range checkFileLimits(limits, oldContent, files[path]) {
+						violations = append(violations, LimitViolation{
+							Organization: j.org,
+							Repository:   task.Name,
+							Branch:       branch.Name,
+							Path:         path,
+							Reason:       reason,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	if limits.MaxWrites > 0 && writes > limits.MaxWrites {
+		for _, j := range jobs {
+			for _, task := range j.todo {
+				for _, branch := range task.Branches {
+					if j.unwritten.Has(fmt.Sprintf("%s@%s", task.Name, branch.Name)) {
+						continue
+					}
+
+					violations = append(violations, LimitViolation{
+						Organization: j.org,
+						Repository:   task.Name,
+						Branch:       branch.Name,
+						Reason:       fmt.Sprintf("%d branches would be written to, but at most %d are allowed per run", writes, limits.MaxWrites),
+					})
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// checkFileLimits compares every alias in the old and new file. Aliases
+// that are removed entirely count as losing all their members.
+func checkFileLimits(limits Limits, oldContent, newContent string) []string {
+	oldAliases, err := prow.FromString(oldContent)
+	if err != nil {
+		return nil
+	}
+
+	newAliases, err := prow.FromString(newContent)
+	if err != nil {
+		return nil
+	}
+
+	reasons := []string{}
+
+	for _, name := range sets.List(sets.KeySet(oldAliases.Aliases)) {
+		oldMembers := logins.LowerSet(oldAliases.Aliases[name])
+		newMembers, exists := newAliases.Aliases[name]
+
+		if limits.RefuseEmptyAliases && len(oldMembers) > 0 && len(newMembers) == 0 {
+			if exists {
+				reasons = append(reasons, fmt.Sprintf("alias %q would have no members", name))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("alias %q would be removed", name))
+			}
+
+			continue
+		}
+
+		if limits.MaxRemovalPercent > 0 && len(oldMembers) > 0 {
+			removed := oldMembers.Difference(logins.LowerSet(newMembers)).Len()
+
+			if percent := removed * 100 / oldMembers.Len(); percent > limits.MaxRemovalPercent {
+				reasons = append(reasons, fmt.Sprintf("%d%% of the members of alias %q would be removed (%d of %d)", percent, name, removed, oldMembers.Len()))
+			}
+		}
+	}
+
+	return reasons
+}
@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+func TestCheckFileLimits(t *testing.T) {
+	testcases := []struct {
+		limits     Limits
+		oldContent string
+		newContent string
+		expected   []string
+	}{
+		{
+			limits:     Limits{MaxRemovalPercent: 50},
+			oldContent: "aliases:\n  sig-a: [alice, bob]\n",
+			newContent: "aliases:\n  sig-a: [alice]\n",
+			expected:   []string{},
+		},
+		{
+			limits:     Limits{MaxRemovalPercent: 50},
+			oldContent: "aliases:\n  sig-a: [alice, bob, carol]\n",
+			newContent: "aliases:\n  sig-a: [Alice, dave]\n",
+			expected:   []string{`66% of the members of alias "sig-a" would be removed (2 of 3)`},
+		},
+		{
+			limits:     Limits{MaxRemovalPercent: 50},
+			oldContent: "aliases:\n  sig-a: [alice]\n  sig-b: [bob]\n",
+			newContent: "aliases:\n  sig-a: [alice]\n",
+			expected:   []string{`100% of the members of alias "sig-b" would be removed (1 of 1)`},
+		},
+		{
+			limits:     Limits{RefuseEmptyAliases: true},
+			oldContent: "aliases:\n  sig-a: [alice]\n  sig-b: [bob]\n  sig-c: []\n",
+			newContent: "aliases:\n  sig-a: []\n  sig-c: []\n",
+			expected:   []string{`alias "sig-a" would have no members`, `alias "sig-b" would be removed`},
+		},
+		{
+			limits:     Limits{},
+			oldContent: "aliases:\n  sig-a: [alice]\n",
+			newContent: "aliases:\n  sig-a: []\n",
+			expected:   []string{},
+		},
+	}
+
+	for i, tt := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			reasons := checkFileLimits(tt.limits, tt.oldContent, tt.newContent)
+			if diff := deep.Equal(reasons, tt.expected); diff != nil {
+				t.Errorf("reasons not equal: %v", diff)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	testcases := []struct {
+		name         string
+		teams        []github.Team
+		limits       Limits
+		ignoreLimits bool
+		dryRun       bool
+		existingPRs  []fake.PullRequest
+		violations   int
+		pushes       int
+	}{
+		{
+			name:       "removal limit",
+			teams:      []github.Team{{Slug: "sig-a", Members: []string{"carol"}}},
+			limits:     Limits{MaxRemovalPercent: 50},
+			violations: 2,
+		},
+		{
+			name:         "ignored removal limit",
+			teams:        []github.Team{{Slug: "sig-a", Members: []string{"carol"}}},
+			limits:       Limits{MaxRemovalPercent: 50},
+			ignoreLimits: true,
+			pushes:       2,
+		},
+		{
+			name:       "empty alias",
+			teams:      []github.Team{{Slug: "sig-a", Members: []string{}}},
+			limits:     Limits{RefuseEmptyAliases: true},
+			violations: 2,
+		},
+		{
+			name:       "no teams",
+			teams:      []github.Team{},
+			limits:     Limits{RefuseEmptyTeams: true},
+			violations: 1,
+		},
+		{
+			name:       "teams without members",
+			teams:      []github.Team{{Slug: "sig-a"}, {Slug: "sig-b", Members: []string{}}},
+			limits:     Limits{RefuseEmptyTeams: true},
+			violations: 1,
+		},
+		{
+			name:       "writes limit",
+			teams:      testTeams(),
+			limits:     Limits{MaxWrites: 1},
+			violations: 2,
+		},
+		{
+			name:   "writes limit ignores open pull requests",
+			teams:  testTeams(),
+			limits: Limits{MaxWrites: 1},
+			existingPRs: []fake.PullRequest{{
+				Org:    testOrg,
+				Repo:   "a",
+				Number: 7,
+				Base:   "main",
+				Head:   "update-main-owners",
+			}},
+			pushes: 1,
+		},
+		{
+			name:   "writes limit ignores dry runs",
+			teams:  testTeams(),
+			limits: Limits{MaxWrites: 1},
+			dryRun: true,
+		},
+		{
+			name:   "within limits",
+			teams:  testTeams(),
+			limits: Limits{MaxRemovalPercent: 50, MaxWrites: 2, RefuseEmptyAliases: true, RefuseEmptyTeams: true},
+			pushes: 2,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			gh := fake.NewGitHub()
+			gh.Teams[testOrg] = testcase.teams
+			gh.Repositories[testOrg] = []github.Repository{
+				{ID: "a-id", Name: "a", Branches: []github.Branch{{Name: "main", MostRecentCommit: time.Now(), Aliases: outdatedAliases}}},
+				{ID: "b-id", Name: "b", Branches: []github.Branch{{Name: "main", MostRecentCommit: time.Now(), Aliases: outdatedAliases}}},
+			}
+			gh.PullRequests = testcase.existingPRs
+
+			gitter := fake.NewGit()
+			gitter.AddRemote(testOrg, "a", map[string]fake.Files{"main": {prow.OwnersAliasesFilename: outdatedAliases}})
+			gitter.AddRemote(testOrg, "b", map[string]fake.Files{"main": {prow.OwnersAliasesFilename: outdatedAliases}})
+
+			clients := Clients{
+				Teams:        gh,
+				Repositories: gh,
+				PullRequests: gh,
+				Git:          gitter,
+			}
+
+			opt := testOptions()
+			opt.Limits = testcase.limits
+			opt.IgnoreLimits = testcase.ignoreLimits
+			opt.DryRun = testcase.dryRun
+
+			_, err := newTestSyncer(t, clients, opt).Run(context.Background())
+
+			if testcase.violations == 0 {
+				if err != nil {
+					t.Fatalf("Failed to synchronize: %v", err)
+				}
+
+				if len(gitter.Pushes) != testcase.pushes {
+					t.Fatalf("Expected %d pushes, got %d.", testcase.pushes, len(gitter.Pushes))
+				}
+
+				return
+			}
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Expected a LimitError, got %v.", err)
+			}
+
+			if len(limitErr.Violations) != testcase.violations {
+				t.Errorf("Expected %d violations, got %+v.", testcase.violations, limitErr.Violations)
+			}
+
+			if len(gitter.Commits) > 0 || len(gitter.Pushes) > 0 {
+				t.Fatal("Nothing must be written if a limit is exceeded.")
+			}
+		})
+	}
+}
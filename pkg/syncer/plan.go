@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const planVersion = 1
+
+// Plan contains all changes a synchronization would make, so that they can
+// be reviewed before they are applied.
+type Plan struct {
+	Version int       `yaml:"version"`
+	Created time.Time `yaml:"created"`
+	// UpdateDirectly is true if the changes are pushed directly into the
+	// branches instead of creating pull requests.
+	UpdateDirectly bool     `yaml:"updateDirectly"`
+	Changes        []Change `yaml:"changes"`
+}
+
+// Change is an update to a single aliases file in a branch. All changes for
+// the same branch are applied in a single commit.
+type Change struct {
+	Organization string `yaml:"organization"`
+	Repository   string `yaml:"repository"`
+	RepositoryID string `yaml:"repositoryID"`
+	Branch       string `yaml:"branch"`
+	// Path is the path of the aliases file; defaults to the OWNERS_ALIASES
+	// file in the root directory.
+	Path string `yaml:"path,omitempty"`
+	// BaseOID is the head commit of the branch when the plan was created;
+	// the change is not applied if the branch has moved since.
+	BaseOID string `yaml:"baseOID"`
+	// Content is the new aliases file.
+	Content string `yaml:"content"`
+	// Diff is a unified diff between the old and new aliases file and only
+	// meant for humans.
+	Diff string `yaml:"diff"`
+}
+
+// LoadPlan reads and validates a plan file.
+func LoadPlan(filename string) (*Plan, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	plan := &Plan{}
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d, expected %d", plan.Version, planVersion)
+	}
+
+	for i, change := range plan.Changes {
+		if change.Path == "" {
+			plan.Changes[i].Path = prow.OwnersAliasesFilename
+		}
+
+		if change.Organization == "" || change.Repository == "" || change.RepositoryID == "" || change.Branch == "" || change.BaseOID == "" {
+			return nil, fmt.Errorf("invalid plan: incomplete change for %s/%s@%s", change.Organization, change.Repository, change.Branch)
+		}
+	}
+
+	return plan, nil
+}
+
+// Encode writes the plan as YAML.
+func (p *Plan) Encode(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(p); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// Organizations returns all organizations that are part of the plan.
+func (p *Plan) Organizations() []string {
+	orgs := []string{}
+
+	for _, change := range p.Changes {
+		if !slices.Contains(orgs, change.Organization) {
+			orgs = append(orgs, change.Organization)
+		}
+	}
+
+	return orgs
+}
+
+// Plan determines all changes without cloning any repository. The state is
+// neither used nor updated.
+func (s *Syncer) Plan(ctx context.Context) (*Plan, *Result, error) {
+	plan := &Plan{
+		Version:        planVersion,
+		Created:        time.Now().UTC().Truncate(time.Second),
+		UpdateDirectly: s.opts.UpdateDirectly,
+		Changes:        []Change{},
+	}
+
+	opts := s.opts
+	opts.State = nil
+
+	result, err := s.run(ctx, Scope{}, opts, func(log logrus.FieldLogger, _ Options, result *Result, org string, repos []github.Repository, todo []github.Repository) error {
+		for _, task := range todo {
+			for _, branch := range task.Branches {
+				files := branch.AliasesFiles()
+
+				for _, path := range sets.List(sets.KeySet(files)) {
+					diff, err := diffAliases(path, findAliases(repos, task.Name, branch.Name, path), files[path])
+					if err != nil {
+						return fmt.Errorf("failed to create diff: %w", err)
+					}
+
+					plan.Changes = append(plan.Changes, Change{
+						Organization: org,
+						Repository:   task.Name,
+						RepositoryID: fmt.Sprintf("%v", task.ID),
+						Branch:       branch.Name,
+						Path:         path,
+						BaseOID:      branch.HeadOID,
+						Content:      files[path],
+						Diff:         diff,
+					})
+				}
+
+				s.emit(result, Event{
+					Organization: org,
+					Repository:   task.Name,
+					Branch:       branch.Name,
+					Decision:     DecisionPlanned,
+				})
+			}
+		}
+
+		return nil
+	})
+
+	return plan, result, err
+}
+
+// Apply performs the changes from a plan. Branches whose head commit has
+// changed since the plan was created are not updated.
+func (s *Syncer) Apply(ctx context.Context, plan *Plan) (*Result, error) {
+	result := &Result{}
+
+	opts := s.opts
+	opts.UpdateDirectly = plan.UpdateDirectly
+
+	for _, org := range plan.Organizations() {
+		tasks := []github.Repository{}
+		taskIndex := map[string]int{}
+
+		for _, change := range plan.Changes {
+			if change.Organization != org {
+				continue
+			}
+
+			if i, exists := taskIndex[change.Repository]; exists {
+				tasks[i].Branches = addChange(tasks[i].Branches, change)
+				continue
+			}
+
+			taskIndex[change.Repository] = len(tasks)
+			tasks = append(tasks, github.Repository{
+				ID:       githubv4.ID(change.RepositoryID),
+				Name:     change.Repository,
+				Branches: addChange(nil, change),
+			})
+		}
+
+		result.Repositories += len(tasks)
+
+		if err := s.processTasks(ctx, s.log.WithField("target", org), opts, result, org, tasks, true); err != nil {
+			return result, fmt.Errorf("failed to process: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// addChange adds the file from a change to its branch, so that all files in
+// a branch are updated in a single commit.
+func addChange(branches []github.Branch, change Change) []github.Branch {
+	for i, b := range branches {
+		if b.Name == change.Branch {
+			branches[i].Files[change.Path] = change.Content
+			return branches
+		}
+	}
+
+	return append(branches, github.Branch{
+		Name:    change.Branch,
+		HeadOID: change.BaseOID,
+		Files: map[string]string{
+			change.Path: change.Content,
+		},
+	})
+}
+
+func findAliases(repos []github.Repository, repo string, branch string, path string) string {
+	for _, r := range repos {
+		if r.Name != repo {
+			continue
+		}
+
+		for _, b := range r.Branches {
+			if b.Name == branch {
+				return b.AliasesFiles()[path]
+			}
+		}
+	}
+
+	return ""
+}
+
+func diffAliases(path string, oldContent, newContent string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldContent),
+		B:        difflib.SplitLines(newContent),
+		FromFile: "a/" + path,
+		ToFile:   "b/" + path,
+		Context:  3,
+	})
+}
@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+func TestPlanAndApply(t *testing.T) {
+	testcases := []struct {
+		name           string
+		updateDirectly bool
+		movedHead      bool
+		expected       Decision
+	}{
+		{
+			name:     "creates pull request",
+			expected: DecisionPullRequestCreated,
+		},
+		{
+			name:           "updates branch directly",
+			updateDirectly: true,
+			expected:       DecisionUpdated,
+		},
+		{
+			name:      "refuses moved branch",
+			movedHead: true,
+			expected:  DecisionFailed,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			gh := fake.NewGitHub()
+			gh.Teams[testOrg] = testTeams()
+			gh.Repositories[testOrg] = []github.Repository{{
+				ID:   "repo-id",
+				Name: "repo",
+				Branches: []github.Branch{
+					{Name: "main", HeadOID: "abc", MostRecentCommit: time.Now(), Aliases: outdatedAliases},
+					{Name: "release-1.0", HeadOID: "def", MostRecentCommit: time.Now(), Aliases: upToDateAliases},
+				},
+			}}
+
+			gitter := fake.NewGit()
+			gitter.AddRemote(testOrg, "repo", map[string]fake.Files{
+				"main": {prow.OwnersAliasesFilename: outdatedAliases},
+			})
+			gitter.SetHead(testOrg, "repo", "main", "abc")
+
+			clients := Clients{
+				Teams:        gh,
+				Repositories: gh,
+				PullRequests: gh,
+				Git:          gitter,
+			}
+
+			opt := testOptions()
+			opt.UpdateDirectly = testcase.updateDirectly
+
+			s := newTestSyncer(t, clients, opt)
+
+			plan, _, err := s.Plan(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to create plan: %v", err)
+			}
+
+			if len(plan.Changes) != 1 {
+				t.Fatalf("Expected exactly one change, got %+v.", plan.Changes)
+			}
+
+			change := plan.Changes[0]
+			if change.Branch != "main" || change.BaseOID != "abc" || change.Content != upToDateAliases {
+				t.Errorf("Unexpected change: %+v", change)
+			}
+
+			if !strings.Contains(change.Diff, "+    - bob") {
+				t.Errorf("Diff does not contain the new member:\n%s", change.Diff)
+			}
+
+			if len(gitter.Commits) > 0 || len(gitter.Pushes) > 0 {
+				t.Fatal("Creating a plan must not modify any repository.")
+			}
+
+			// the plan must survive a roundtrip through a file
+			filename := filepath.Join(t.TempDir(), "plan.yaml")
+
+			f, err := os.Create(filename)
+			if err != nil {
+				t.Fatalf("Failed to create plan file: %v", err)
+			}
+
+			if err := plan.Encode(f); err != nil {
+				t.Fatalf("Failed to encode plan: %v", err)
+			}
+			f.Close()
+
+			loaded, err := LoadPlan(filename)
+			if err != nil {
+				t.Fatalf("Failed to load plan: %v", err)
+			}
+
+			if diff := deep.Equal(loaded, plan); diff != nil {
+				t.Fatalf("plan not equal after loading: %v", diff)
+			}
+
+			if testcase.movedHead {
+				gitter.SetHead(testOrg, "repo", "main", "xyz")
+			}
+
+			// apply must not depend on the options used for creating the plan
+			result, err := newTestSyncer(t, clients, testOptions()).Apply(context.Background(), loaded)
+			if err != nil {
+				t.Fatalf("Failed to apply plan: %v", err)
+			}
+
+			if len(result.Branches) != 1 || result.Branches[0].Decision != testcase.expected {
+				t.Fatalf("Expected a single %q decision, got %+v.", testcase.expected, result.Branches)
+			}
+
+			if testcase.movedHead && len(gitter.Pushes) > 0 {
+				t.Fatal("A moved branch must not be updated.")
+			}
+		})
+	}
+}
+
+func TestPlanAliasesPaths(t *testing.T) {
+	gh := fake.NewGitHub()
+	gh.Teams[testOrg] = testTeams()
+	gh.Repositories[testOrg] = []github.Repository{{
+		ID:   "repo-id",
+		Name: "repo",
+		Branches: []github.Branch{{
+			Name:             "main",
+			HeadOID:          "abc",
+			MostRecentCommit: time.Now(),
+			Aliases:          outdatedAliases,
+			Files: map[string]string{
+				"docs/OWNERS_ALIASES": outdatedAliases,
+			},
+		}},
+	}}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote(testOrg, "repo", map[string]fake.Files{
+		"main": {
+			prow.OwnersAliasesFilename: outdatedAliases,
+			"docs/OWNERS_ALIASES":      outdatedAliases,
+		},
+	})
+	gitter.SetHead(testOrg, "repo", "main", "abc")
+
+	clients := Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+		Files:        gh,
+	}
+
+	opt := testOptions()
+	opt.AliasesPaths = []string{"**/OWNERS_ALIASES"}
+
+	plan, _, err := newTestSyncer(t, clients, opt).Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to create plan: %v", err)
+	}
+
+	paths := []string{}
+	for _, change := range plan.Changes {
+		paths = append(paths, change.Path)
+
+		if !strings.Contains(change.Diff, "+++ b/"+change.Path) {
+			t.Errorf("Diff does not refer to %s:\n%s", change.Path, change.Diff)
+		}
+	}
+
+	if diff := deep.Equal(paths, []string{prow.OwnersAliasesFilename, "docs/OWNERS_ALIASES"}); diff != nil {
+		t.Fatalf("changed paths not equal: %v", diff)
+	}
+
+	result, err := newTestSyncer(t, clients, testOptions()).Apply(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("Failed to apply plan: %v", err)
+	}
+
+	if len(result.Branches) != 1 || result.Branches[0].Decision != DecisionPullRequestCreated {
+		t.Fatalf("Expected a single pull request, got %+v.", result.Branches)
+	}
+
+	if len(gitter.Commits) != 1 {
+		t.Fatalf("Expected all files to be updated in a single commit, got %+v.", gitter.Commits)
+	}
+
+	expected := fake.Files{
+		prow.OwnersAliasesFilename: upToDateAliases,
+		"docs/OWNERS_ALIASES":      upToDateAliases,
+	}
+
+	if diff := deep.Equal(gitter.Pushes[0].Files, expected); diff != nil {
+		t.Errorf("pushed files not equal: %v", diff)
+	}
+}
@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/git"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// processTasks updates the aliases files in the given branches. If
+// verifyHeads is set, branches whose head commit differs from the HeadOID
+// are not updated.
+func (s *Syncer) processTasks(ctx context.Context, log logrus.FieldLogger, opts Options, result *Result, org string, tasks []github.Repository, verifyHeads bool) error {
+	tmpDir, err := os.MkdirTemp("", "xrstf*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	gitter := s.clients.Git
+
+	for _, task := range tasks {
+		tlog := log.WithField("repo", task.Name)
+		tlog.Info("Processing…")
+
+		cloned := false
+
+		repoURL := gitter.RepositoryURL(org, task.Name)
+		repoDir := filepath.Join(tmpDir, task.Name)
+
+		for _, branch := range task.Branches {
+			blog := tlog.WithField("branch", branch.Name)
+
+			event := Event{
+				Organization: org,
+				Repository:   task.Name,
+				Branch:       branch.Name,
+			}
+
+			fail := func(err error) {
+				event.Decision = DecisionFailed
+				event.Error = err
+				s.emit(result, event)
+			}
+
+			data := templateData(org, task.Name, branch)
+
+			newBranch, err := renderTemplate(opts.HeadBranch, data)
+			if err != nil {
+				blog.WithError(err).Error("Failed to render head branch template.")
+				fail(fmt.Errorf("failed to render head branch template: %w", err))
+				continue
+			}
+
+			if newBranch == "" {
+				blog.Error("Head branch template resulted in an empty branch name.")
+				fail(errors.New("head branch template resulted in an empty branch name"))
+				continue
+			}
+
+			data.HeadBranch = newBranch
+
+			direct := opts.UpdateDirectly
+			if direct && s.clients.Protection != nil {
+				allowed, reason, err := s.clients.Protection.CanPushDirectly(org, task.Name, branch.Name, opts.SignedCommits)
+				if err != nil {
+					blog.WithError(err).Warn("Failed to check branch protection.")
+					fail(fmt.Errorf("failed to check branch protection: %w", err))
+					continue
+				}
+
+				if !allowed {
+					blog.WithField("reason", reason).Info("Branch cannot be updated directly, using a pull request instead.")
+					direct = false
+					event.Fallback = true
+					event.Reason = reason
+				}
+			}
+
+			commitMsg, err := renderTemplate(opts.CommitMessage, data)
+			if err != nil {
+				blog.WithError(err).Error("Failed to render commit message template.")
+				fail(fmt.Errorf("failed to render commit message template: %w", err))
+				continue
+			}
+
+			if !direct {
+				event.HeadBranch = newBranch
+
+				prNumber, err := s.clients.PullRequests.GetPullRequestForBranch(org, task.Name, branch.Name, newBranch)
+				if err != nil {
+					blog.WithError(err).Warn("Failed to check for existing pull request.")
+					fail(fmt.Errorf("failed to check for existing pull request: %w", err))
+					continue
+				}
+
+				if prNumber > 0 {
+					blog.WithField("pr", prNumber).Info("Pull request already open.")
+					event.Decision = DecisionPullRequestExists
+					event.PullRequest = prNumber
+					s.emit(result, event)
+					continue
+				}
+			}
+
+			if !cloned {
+				tlog.Debug("Cloning…")
+				if err := gitter.CloneRepository(repoURL, repoDir); err != nil {
+					tlog.WithError(err).Warn("Failed to clone repository.")
+					fail(fmt.Errorf("failed to clone repository: %w", err))
+					continue
+				}
+
+				cloned = true
+			}
+
+			// just for safety
+			if err := gitter.ResetRepository(repoDir); err != nil {
+				blog.WithError(err).Warn("Failed to reset working copy.")
+				fail(fmt.Errorf("failed to reset working copy: %w", err))
+				continue
+			}
+
+			if err := gitter.CheckoutBranch(repoDir, branch.Name); err != nil {
+				blog.WithError(err).Warn("Failed to checkout branch.")
+				fail(fmt.Errorf("failed to checkout branch: %w", err))
+				continue
+			}
+
+			if verifyHeads {
+				head, err := gitter.HeadCommit(repoDir)
+				if err != nil {
+					blog.WithError(err).Warn("Failed to determine head commit.")
+					fail(fmt.Errorf("failed to determine head commit: %w", err))
+					continue
+				}
+
+				if head != branch.HeadOID {
+					blog.WithField("expected", branch.HeadOID).WithField("head", head).Warn("Branch has changed since the plan was created.")
+					fail(fmt.Errorf("branch head is %s, but the plan was created for %s", head, branch.HeadOID))
+					continue
+				}
+			}
+
+			if !direct {
+				if err := gitter.CreateBranch(repoDir, newBranch); err != nil {
+					blog.WithError(err).Warn("Failed to create new branch.")
+					fail(fmt.Errorf("failed to create new branch: %w", err))
+					continue
+				}
+			}
+
+			if err := writeFiles(gitter, repoDir, branch.AliasesFiles()); err != nil {
+				blog.WithError(err).Warn("Failed to update file.")
+				fail(fmt.Errorf("failed to update file: %w", err))
+				continue
+			}
+
+			if err := gitter.Commit(repoDir, commitMsg); err != nil {
+				blog.WithError(err).Warn("Failed to commit changes.")
+				fail(fmt.Errorf("failed to commit changes: %w", err))
+				continue
+			}
+
+			if opts.DryRun {
+				if !direct {
+					blog = blog.WithField("new-branch", newBranch)
+				}
+
+				blog.Info("Dry run, not pushing branch.")
+				event.Decision = DecisionDryRun
+				s.emit(result, event)
+				continue
+			}
+
+			pushBranch := newBranch
+			if direct {
+				pushBranch = branch.Name
+			}
+
+			if err := gitter.Push(repoDir, "origin", pushBranch); err != nil {
+				blog.WithError(err).Warn("Failed to push changes.")
+				fail(fmt.Errorf("failed to push changes: %w", err))
+				continue
+			}
+
+			result.Pushes++
+
+			if direct {
+				blog.Info("Branch updated.")
+				event.Decision = DecisionUpdated
+				s.emit(result, event)
+			} else {
+				title, err := renderTemplate(opts.Title, data)
+				if err != nil {
+					blog.WithError(err).Error("Failed to render title template.")
+					fail(fmt.Errorf("failed to render title template: %w", err))
+					continue
+				}
+
+				body, err := renderTemplate(opts.Body, data)
+				if err != nil {
+					blog.WithError(err).Error("Failed to render body template.")
+					fail(fmt.Errorf("failed to render body template: %w", err))
+					continue
+				}
+
+				prNumber, err := s.clients.PullRequests.CreatePullRequest(task.ID, branch.Name, newBranch, title, body)
+				if err != nil {
+					blog.WithError(err).Warn("Failed to create pull request.")
+					fail(fmt.Errorf("failed to create pull request: %w", err))
+					continue
+				}
+
+				blog.WithField("pr", prNumber).Info("Pull request created.")
+				event.Decision = DecisionPullRequestCreated
+				event.PullRequest = prNumber
+				s.emit(result, event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// templateData returns the data for rendering the templates for a branch
+// with the new aliases files; the HeadBranch is not set yet.
+func templateData(org, repo string, branch github.Branch) TemplateData {
+	filenames := sets.List(sets.KeySet(branch.AliasesFiles()))
+
+	return TemplateData{
+		Filename:   strings.Join(filenames, ", "),
+		Filenames:  filenames,
+		BaseBranch: branch.Name,
+		Org:        org,
+		Repo:       repo,
+	}
+}
+
+func writeFiles(gitter git.RepositoryWriter, repoDir string, files map[string]string) error {
+	for _, path := range sets.List(sets.KeySet(files)) {
+		if err := gitter.WriteFile(repoDir, path, files[path]); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
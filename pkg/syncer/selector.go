@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+// BranchSelector decides which branches are synchronized. Selectors can be
+// combined using AnyOf and Excluding.
+type BranchSelector interface {
+	// Select returns a description of the rule that selected the branch,
+	// or an empty string if the branch is not selected.
+	Select(org string, repo github.Repository, branch github.Branch) string
+	// String describes the selector.
+	String() string
+}
+
+type globSelector []string
+
+// GlobSelector selects branches whose names match any of the glob patterns.
+func GlobSelector(patterns ...string) BranchSelector {
+	return globSelector(patterns)
+}
+
+func (s globSelector) Select(_ string, _ github.Repository, branch github.Branch) string {
+	for _, pattern := range s {
+		if matched, _ := filepath.Match(pattern, branch.Name); matched {
+			return fmt.Sprintf("glob:%s", pattern)
+		}
+	}
+
+	return ""
+}
+
+func (s globSelector) String() string {
+	return fmt.Sprintf("glob%v", []string(s))
+}
+
+type regexSelector []*regexp.Regexp
+
+// RegexSelector selects branches whose names match any of the regular
+// expressions.
+func RegexSelector(exprs ...*regexp.Regexp) BranchSelector {
+	return regexSelector(exprs)
+}
+
+func (s regexSelector) Select(_ string, _ github.Repository, branch github.Branch) string {
+	for _, expr := range s {
+		if expr.MatchString(branch.Name) {
+			return fmt.Sprintf("regex:%s", expr)
+		}
+	}
+
+	return ""
+}
+
+func (s regexSelector) String() string {
+	exprs := []string{}
+	for _, expr := range s {
+		exprs = append(exprs, expr.String())
+	}
+
+	return fmt.Sprintf("regex%v", exprs)
+}
+
+type defaultBranchSelector struct{}
+
+// DefaultBranchSelector selects the default branch of each repository.
+func DefaultBranchSelector() BranchSelector {
+	return defaultBranchSelector{}
+}
+
+func (defaultBranchSelector) Select(_ string, repo github.Repository, branch github.Branch) string {
+	if repo.DefaultBranch != "" && branch.Name == repo.DefaultBranch {
+		return "default-branch"
+	}
+
+	return ""
+}
+
+func (defaultBranchSelector) String() string {
+	return "default-branch"
+}
+
+type protectedBranchSelector struct{}
+
+// ProtectedBranchSelector selects branches that are covered by a branch
+// protection rule.
+func ProtectedBranchSelector() BranchSelector {
+	return protectedBranchSelector{}
+}
+
+func (protectedBranchSelector) Select(_ string, _ github.Repository, branch github.Branch) string {
+	if branch.Protected {
+		return "protected"
+	}
+
+	return ""
+}
+
+func (protectedBranchSelector) String() string {
+	return "protected"
+}
+
+type prowConfigSelector struct {
+	config *prow.Config
+}
+
+// ProwConfigSelector selects branches that are part of a Tide query or the
+// branch protection section in a Prow config.
+func ProwConfigSelector(config *prow.Config) BranchSelector {
+	return prowConfigSelector{config: config}
+}
+
+func (s prowConfigSelector) Select(org string, repo github.Repository, branch github.Branch) string {
+	if section := s.config.MatchBranch(org, repo.Name, branch.Name); section != "" {
+		return fmt.Sprintf("prow-config:%s", section)
+	}
+
+	return ""
+}
+
+func (s prowConfigSelector) String() string {
+	// include the content, so that the state is discarded when the Prow
+	// config changes
+	data, _ := json.Marshal(s.config)
+	hash := sha256.Sum256(data)
+
+	return fmt.Sprintf("prow-config:%s", hex.EncodeToString(hash[:8]))
+}
+
+type anySelector []BranchSelector
+
+// AnyOf selects branches that are selected by any of the given selectors;
+// the first matching selector is reported.
+func AnyOf(selectors ...BranchSelector) BranchSelector {
+	return anySelector(selectors)
+}
+
+func (s anySelector) Select(org string, repo github.Repository, branch github.Branch) string {
+	for _, selector := range s {
+		if matched := selector.Select(org, repo, branch); matched != "" {
+			return matched
+		}
+	}
+
+	return ""
+}
+
+func (s anySelector) String() string {
+	parts := []string{}
+	for _, selector := range s {
+		parts = append(parts, selector.String())
+	}
+
+	return fmt.Sprintf("any(%s)", strings.Join(parts, ", "))
+}
+
+type excludingSelector struct {
+	selector BranchSelector
+	exclude  BranchSelector
+}
+
+// Excluding selects the branches selected by the first selector, unless
+// they are also selected by the second one.
+func Excluding(selector BranchSelector, exclude BranchSelector) BranchSelector {
+	return excludingSelector{selector: selector, exclude: exclude}
+}
+
+func (s excludingSelector) Select(org string, repo github.Repository, branch github.Branch) string {
+	if s.exclude.Select(org, repo, branch) != "" {
+		return ""
+	}
+
+	return s.selector.Select(org, repo, branch)
+}
+
+func (s excludingSelector) String() string {
+	return fmt.Sprintf("%s excluding %s", s.selector, s.exclude)
+}
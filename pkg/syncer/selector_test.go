@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"regexp"
+	"testing"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+func TestBranchSelectors(t *testing.T) {
+	prowConfig := &prow.Config{}
+	prowConfig.Tide.Queries = []prow.TideQuery{{
+		Repos:            []string{testOrg + "/repo"},
+		IncludedBranches: []string{"develop"},
+	}}
+
+	repo := github.Repository{
+		Name:          "repo",
+		DefaultBranch: "main",
+	}
+
+	testcases := []struct {
+		name     string
+		selector BranchSelector
+		branch   github.Branch
+		expected string
+	}{
+		{
+			name:     "glob",
+			selector: GlobSelector("main", "release-*"),
+			branch:   github.Branch{Name: "release-1.0"},
+			expected: "glob:release-*",
+		},
+		{
+			name:     "glob without match",
+			selector: GlobSelector("main", "release-*"),
+			branch:   github.Branch{Name: "feature"},
+			expected: "",
+		},
+		{
+			name:     "regex",
+			selector: RegexSelector(regexp.MustCompile(`^release-\d+\.\d+$`)),
+			branch:   github.Branch{Name: "release-1.0"},
+			expected: `regex:^release-\d+\.\d+$`,
+		},
+		{
+			name:     "default branch",
+			selector: DefaultBranchSelector(),
+			branch:   github.Branch{Name: "main"},
+			expected: "default-branch",
+		},
+		{
+			name:     "not the default branch",
+			selector: DefaultBranchSelector(),
+			branch:   github.Branch{Name: "develop"},
+			expected: "",
+		},
+		{
+			name:     "protected branch",
+			selector: ProtectedBranchSelector(),
+			branch:   github.Branch{Name: "develop", Protected: true},
+			expected: "protected",
+		},
+		{
+			name:     "prow config",
+			selector: ProwConfigSelector(prowConfig),
+			branch:   github.Branch{Name: "develop"},
+			expected: "prow-config:tide",
+		},
+		{
+			name:     "first matching selector is reported",
+			selector: AnyOf(GlobSelector("feature-*"), DefaultBranchSelector(), GlobSelector("ma*")),
+			branch:   github.Branch{Name: "main"},
+			expected: "default-branch",
+		},
+		{
+			name:     "excluded branch",
+			selector: Excluding(GlobSelector("release-*"), GlobSelector("release-0.*")),
+			branch:   github.Branch{Name: "release-0.9"},
+			expected: "",
+		},
+		{
+			name:     "branch not excluded",
+			selector: Excluding(GlobSelector("release-*"), GlobSelector("release-0.*")),
+			branch:   github.Branch{Name: "release-1.0"},
+			expected: "glob:release-*",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			if selected := testcase.selector.Select(testOrg, repo, testcase.branch); selected != testcase.expected {
+				t.Fatalf("Expected %q, got %q.", testcase.expected, selected)
+			}
+		})
+	}
+}
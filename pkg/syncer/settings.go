@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+// markerPrefix starts a comment in an aliases file that controls how the
+// file is synchronized, e.g. "# prow-aliases-syncer: keep exclude=foo,bar".
+const markerPrefix = "prow-aliases-syncer:"
+
+// Settings allow repository owners to control the synchronization of their
+// repository. They are read from the settings file (github.SettingsFilename)
+// in each branch; branches without the file use the settings of the default
+// branch.
+type Settings struct {
+	// Ignore disables the synchronization of the branch. On the default
+	// branch, this disables the entire repository.
+	Ignore bool `yaml:"ignore"`
+	// IgnoreBranches are glob patterns for branches that should not be
+	// synchronized. This is only evaluated on the default branch.
+	IgnoreBranches []string `yaml:"ignoreBranches"`
+	// ExcludeAliases are never modified by the syncer.
+	ExcludeAliases []string `yaml:"excludeAliases"`
+	// Keep and Strict override the global options of the same name.
+	Keep   *bool `yaml:"keep"`
+	Strict *bool `yaml:"strict"`
+}
+
+// fileSettings are the effective settings for a single aliases file.
+type fileSettings struct {
+	ignore   bool
+	excluded []string
+	keep     bool
+	strict   bool
+	// markers are the marker comments, which are preserved when the file
+	// is updated.
+	markers []string
+}
+
+func parseSettings(content string) (*Settings, error) {
+	settings := &Settings{}
+
+	decoder := yaml.NewDecoder(bytes.NewBufferString(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(settings); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for _, pattern := range settings.IgnoreBranches {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+	}
+
+	return settings, nil
+}
+
+// branchSettings determines the settings for a branch. If the branch opted
+// out of the synchronization, the reason is returned.
+func branchSettings(repo github.Repository, branch github.Branch) (*Settings, string, error) {
+	repoSettings := &Settings{}
+
+	for _, b := range repo.Branches {
+		if b.Name == repo.DefaultBranch && b.Settings != "" {
+			var err error
+
+			repoSettings, err = parseSettings(b.Settings)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid %s on %s: %w", github.SettingsFilename, b.Name, err)
+			}
+		}
+	}
+
+	if repoSettings.Ignore {
+		return nil, "repository opted out", nil
+	}
+
+	for _, pattern := range repoSettings.IgnoreBranches {
+		if matched, _ := filepath.Match(pattern, branch.Name); matched {
+			return nil, fmt.Sprintf("branch is ignored by repository settings (%s)", pattern), nil
+		}
+	}
+
+	if branch.Name == repo.DefaultBranch || branch.Settings == "" {
+		return repoSettings, "", nil
+	}
+
+	settings, err := parseSettings(branch.Settings)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid %s: %w", github.SettingsFilename, err)
+	}
+
+	if settings.Ignore {
+		return nil, "branch opted out", nil
+	}
+
+	return settings, "", nil
+}
+
+// forFile combines the settings with the global options and the marker
+// comments in the aliases file.
+func (s *Settings) forFile(opts Options, content string) (fileSettings, error) {
+	result := fileSettings{
+		excluded: append([]string{}, s.ExcludeAliases...),
+		keep:     opts.Keep,
+		strict:   opts.Strict,
+	}
+
+	if s.Keep != nil {
+		result.keep = *s.Keep
+	}
+
+	if s.Strict != nil {
+		result.strict = *s.Strict
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		comment, isComment := strings.CutPrefix(strings.TrimSpace(line), "#")
+		if !isComment {
+			continue
+		}
+
+		marker, isMarker := strings.CutPrefix(strings.TrimSpace(comment), markerPrefix)
+		if !isMarker {
+			continue
+		}
+
+		for _, field := range strings.Fields(marker) {
+			switch key, value, _ := strings.Cut(field, "="); key {
+			case "ignore":
+				result.ignore = true
+			case "keep":
+				result.keep = true
+			case "strict":
+				result.strict = true
+			case "exclude":
+				for _, name := range strings.Split(value, ",") {
+					if name != "" {
+						result.excluded = append(result.excluded, name)
+					}
+				}
+			default:
+				return result, fmt.Errorf("unknown marker %q", field)
+			}
+		}
+
+		result.markers = append(result.markers, strings.TrimSpace(line))
+	}
+
+	return result, nil
+}
+
+// header returns the file header, followed by the marker comments.
+func (f fileSettings) header(header string) string {
+	if len(f.markers) == 0 {
+		return header
+	}
+
+	return strings.TrimSpace(strings.TrimSpace(header) + "\n" + strings.Join(f.markers, "\n"))
+}
@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"testing"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+func TestMarkersArePreserved(t *testing.T) {
+	files := map[string]string{
+		"OWNERS_ALIASES": "# prow-aliases-syncer: keep\n" + outdatedAliases + "  sig-b:\n    - carol\n",
+	}
+
+	opt := testOptions()
+	opt.Header = DefaultFileHeader
+
+	settings, err := fileSettingsFor(fake.Logger(), files, &Settings{}, opt)
+	if err != nil {
+		t.Fatalf("Failed to determine settings: %v", err)
+	}
+
+	changed, err := updateFiles(fake.Logger(), files, settings, []github.Team{{Slug: "sig-a", Members: []string{"alice", "bob"}}}, opt)
+	if err != nil {
+		t.Fatalf("Failed to update files: %v", err)
+	}
+
+	expected := `# This file was automatically generated by prow-aliases-syncer. DO NOT EDIT.
+# prow-aliases-syncer: keep
+
+aliases:
+  sig-a:
+    - alice
+    - bob
+  sig-b:
+    - carol
+`
+
+	if changed["OWNERS_ALIASES"] != expected {
+		t.Fatalf("Expected\n\n%s\n\nbut got\n\n%s", expected, changed["OWNERS_ALIASES"])
+	}
+}
@@ -0,0 +1,304 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const stateVersion = 1
+
+// State remembers what the syncer saw in previous runs, so that repositories
+// whose branches and relevant teams did not change can be skipped.
+type State struct {
+	lock sync.Mutex
+
+	Version int `json:"version"`
+	// ConfigHash identifies the options the state was recorded with; if
+	// they change, the state is discarded.
+	ConfigHash string `json:"configHash,omitempty"`
+	// Organizations maps target organizations to repositories to branches.
+	Organizations map[string]map[string]map[string]BranchState `json:"organizations"`
+}
+
+type BranchState struct {
+	HeadOID string `json:"headOID"`
+	// Aliases are the names of all aliases in the branch's aliases files.
+	Aliases []string `json:"aliases,omitempty"`
+	// TeamsHash is a hash of the members of all teams that are relevant for
+	// the aliases file.
+	TeamsHash string   `json:"teamsHash"`
+	Decision  Decision `json:"decision"`
+}
+
+func NewState() *State {
+	return &State{
+		Version:       stateVersion,
+		Organizations: map[string]map[string]map[string]BranchState{},
+	}
+}
+
+// LoadState reads a state file. If the file does not exist or was written
+// by an incompatible version, an empty state is returned.
+func LoadState(filename string) (*State, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return NewState(), nil
+		}
+
+		return nil, err
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+
+	if state.Version != stateVersion || state.Organizations == nil {
+		return NewState(), nil
+	}
+
+	return state, nil
+}
+
+// Save writes the state to a file. The file is replaced atomically, so an
+// interrupted write does not leave a broken state behind.
+func (s *State) Save(filename string) error {
+	s.lock.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filename)
+}
+
+// reset discards the state if it was recorded with different options.
+func (s *State) reset(configHash string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ConfigHash != configHash {
+		s.ConfigHash = configHash
+		s.Organizations = map[string]map[string]map[string]BranchState{}
+	}
+}
+
+// hasOrganization returns true if a previous run recorded the organization.
+func (s *State) hasOrganization(org string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, exists := s.Organizations[org]
+
+	return exists
+}
+
+// unchanged returns true if the branches of the repository have the same
+// heads as in the previous run, the relevant teams did not change and all
+// branches were up-to-date (or skipped for other reasons). Stale branches
+// are only considered unchanged if recheckStale is false, as activity
+// signals like tags can change without a new commit.
+func (s *State) unchanged(org string, repo github.Repository, teams []github.Team, recheckStale bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	branches, exists := s.Organizations[org][repo.Name]
+	if !exists || len(branches) != len(repo.Branches) {
+		return false
+	}
+
+	for _, b := range repo.Branches {
+		previous, exists := branches[b.Name]
+		if !exists || previous.HeadOID != b.HeadOID {
+			return false
+		}
+
+		// the branch has to be checked again, because the previous run
+		// might have failed to update it
+		switch previous.Decision {
+		case DecisionOutOfSync, DecisionFailed:
+			return false
+		case DecisionStale:
+			if recheckStale {
+				return false
+			}
+		}
+
+		if previous.TeamsHash != teamsHash(previous.Aliases, teams) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// forget removes all repositories of an organization except the given ones.
+func (s *State) forget(org string, keep sets.Set[string]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	repos := map[string]map[string]BranchState{}
+	for name, branches := range s.Organizations[org] {
+		if keep.Has(name) {
+			repos[name] = branches
+		}
+	}
+
+	s.Organizations[org] = repos
+}
+
+// record remembers the decision for a branch.
+func (s *State) record(org string, repo string, branch github.Branch, teams []github.Team, decision Decision) {
+	var aliases []string
+
+	// the files are only relevant if the syncer actually looked at them
+	switch decision {
+	case DecisionUpToDate, DecisionOutOfSync:
+		names := sets.New[string]()
+		for _, content := range branch.AliasesFiles() {
+			if parsed, err := prow.FromString(content); err == nil {
+				names.Insert(sets.List(sets.KeySet(parsed.Aliases))...)
+			}
+		}
+
+		aliases = sets.List(names)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.Organizations[org] == nil {
+		s.Organizations[org] = map[string]map[string]BranchState{}
+	}
+
+	if s.Organizations[org][repo] == nil {
+		s.Organizations[org][repo] = map[string]BranchState{}
+	}
+
+	s.Organizations[org][repo][branch.Name] = BranchState{
+		HeadOID:   branch.HeadOID,
+		Aliases:   aliases,
+		TeamsHash: teamsHash(aliases, teams),
+		Decision:  decision,
+	}
+}
+
+// teamsHash hashes the members of the teams with the given names. Names
+// without a matching team are part of the hash as well, so that a team
+// being created or deleted changes the hash.
+func teamsHash(names []string, teams []github.Team) string {
+	members := map[string][]string{}
+	for _, team := range teams {
+		members[team.Slug] = team.Members
+	}
+
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, name := range sorted {
+		m, exists := members[name]
+		if !exists {
+			fmt.Fprintf(hash, "%s:-\n", name)
+			continue
+		}
+
+		lower := sets.New[string]()
+		for _, member := range m {
+			lower.Insert(strings.ToLower(member))
+		}
+
+		fmt.Fprintf(hash, "%s:%s\n", name, strings.Join(sets.List(lower), ","))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// configHash identifies all options that influence which branches are
+// considered and how their aliases files are generated.
+func configHash(opts Options) string {
+	messages := []string{}
+	for _, expr := range opts.IgnoredMessages {
+		messages = append(messages, expr.String())
+	}
+
+	data, _ := json.Marshal(struct {
+		Organizations     []string
+		AliasSource       string
+		AliasNaming       string
+		ConflictPolicy    string
+		Branches          []string
+		BranchSelector    string
+		AliasesPaths      []string
+		IgnoredUsers      []string
+		IgnoredCommitters []string
+		IgnoredEmails     []string
+		IgnoredMessages   []string
+		ActivitySignals   []ActivitySignal
+		MaxAge            string
+		PeekDepth         int
+		Config            *config.Config
+		Header            string
+		Strict            bool
+		Keep              bool
+	}{
+		Organizations:     opts.Organizations,
+		AliasSource:       string(opts.AliasSource),
+		AliasNaming:       string(opts.AliasNaming),
+		ConflictPolicy:    string(opts.ConflictPolicy),
+		Branches:          opts.Branches,
+		BranchSelector:    opts.BranchSelector.String(),
+		AliasesPaths:      opts.AliasesPaths,
+		IgnoredUsers:      opts.IgnoredUsers,
+		IgnoredCommitters: opts.IgnoredCommitters,
+		IgnoredEmails:     opts.IgnoredEmails,
+		IgnoredMessages:   messages,
+		ActivitySignals:   opts.ActivitySignals,
+		MaxAge:            opts.MaxAge.String(),
+		PeekDepth:         opts.PeekDepth,
+		Config:            opts.Config,
+		Header:            opts.Header,
+		Strict:            opts.Strict,
+		Keep:              opts.Keep,
+	})
+
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+func decisions(result *Result) map[string]Decision {
+	d := map[string]Decision{}
+	for _, b := range result.Branches {
+		d[b.Repository+"/"+b.Branch] = b.Decision
+	}
+
+	return d
+}
+
+func TestRunWithState(t *testing.T) {
+	gh := fake.NewGitHub()
+	gh.Teams[testOrg] = testTeams()
+	gh.Repositories[testOrg] = []github.Repository{
+		{
+			ID:   "a-id",
+			Name: "a",
+			Branches: []github.Branch{
+				{Name: "main", HeadOID: "a1", MostRecentCommit: time.Now(), Aliases: upToDateAliases},
+				{Name: "feature", HeadOID: "a2", MostRecentCommit: time.Now(), Aliases: upToDateAliases},
+			},
+		},
+		{
+			ID:   "b-id",
+			Name: "b",
+			Branches: []github.Branch{
+				{Name: "main", HeadOID: "b1", MostRecentCommit: time.Now(), Aliases: outdatedAliases},
+			},
+		},
+	}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote(testOrg, "a", map[string]fake.Files{
+		"main": {prow.OwnersAliasesFilename: outdatedAliases},
+	})
+	gitter.AddRemote(testOrg, "b", map[string]fake.Files{
+		"main": {prow.OwnersAliasesFilename: outdatedAliases},
+	})
+
+	clients := Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+	}
+
+	opt := testOptions()
+	opt.State = NewState()
+
+	run := func(opt Options) *Result {
+		result, err := newTestSyncer(t, clients, opt).Run(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to synchronize: %v", err)
+		}
+
+		return result
+	}
+
+	// the first run has to scan everything
+	result := run(opt)
+	expected := map[string]Decision{
+		"a/main":    DecisionUpToDate,
+		"a/feature": DecisionIgnored,
+		"b/main":    DecisionPullRequestCreated,
+	}
+
+	if diff := deep.Equal(decisions(result), expected); diff != nil {
+		t.Fatalf("first run: %v", diff)
+	}
+
+	// modify the file without changing the head to see if the repository is
+	// really skipped; b has to be checked again because it was out of sync
+	gh.Repositories[testOrg][0].Branches[0].Aliases = outdatedAliases
+
+	result = run(opt)
+	expected = map[string]Decision{
+		"a/main":    DecisionUnchanged,
+		"a/feature": DecisionUnchanged,
+		"b/main":    DecisionPullRequestExists,
+	}
+
+	if diff := deep.Equal(decisions(result), expected); diff != nil {
+		t.Fatalf("second run: %v", diff)
+	}
+
+	if result.Repositories != 1 {
+		t.Errorf("Expected 1 scanned repository, got %d.", result.Repositories)
+	}
+
+	// --full ignores the state
+	fullOpt := opt
+	fullOpt.Full = true
+
+	result = run(fullOpt)
+	if decision := decisions(result)["a/main"]; decision != DecisionPullRequestCreated {
+		t.Fatalf("Expected a full run to update a/main, but got %q.", decision)
+	}
+
+	// a full run also updates the state; a/main is now out of sync
+	gh.Repositories[testOrg][0].Branches[0].Aliases = upToDateAliases
+
+	result = run(opt)
+	if decision := decisions(result)["a/main"]; decision != DecisionUpToDate {
+		t.Fatalf("Expected a/main to be checked again, but got %q.", decision)
+	}
+
+	// changing a relevant team invalidates the state
+	gh.Teams[testOrg] = []github.Team{
+		{Slug: "sig-a", Members: []string{"alice"}},
+	}
+
+	result = run(opt)
+	if decision := decisions(result)["a/main"]; decision == DecisionUnchanged {
+		t.Fatal("Expected a/main to be checked again after the team changed.")
+	}
+
+	// changing the options invalidates the state
+	strictOpt := opt
+	strictOpt.Strict = true
+
+	result = run(strictOpt)
+	if decision := decisions(result)["a/feature"]; decision != DecisionIgnored {
+		t.Fatalf("Expected a/feature to be checked again after the options changed, but got %q.", decision)
+	}
+}
+
+func TestStateSaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadState(filename)
+	if err != nil {
+		t.Fatalf("Failed to load missing state: %v", err)
+	}
+
+	state.reset("config")
+	state.record(testOrg, "repo", github.Branch{Name: "main", HeadOID: "abc", Aliases: upToDateAliases}, testTeams(), DecisionUpToDate)
+
+	if err := state.Save(filename); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	loaded, err := LoadState(filename)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	if diff := deep.Equal(loaded.Organizations, state.Organizations); diff != nil {
+		t.Fatalf("not equal: %v", diff)
+	}
+
+	if loaded.Organizations[testOrg]["repo"]["main"].Aliases[0] != "sig-a" {
+		t.Errorf("Expected aliases to be recorded, got %+v.", loaded.Organizations[testOrg]["repo"]["main"])
+	}
+}
+
+func TestStateUnchanged(t *testing.T) {
+	branch := github.Branch{Name: "main", HeadOID: "abc", Aliases: upToDateAliases}
+	repo := github.Repository{Name: "repo", Branches: []github.Branch{branch}}
+
+	testcases := []struct {
+		decision     Decision
+		recheckStale bool
+		expected     bool
+	}{
+		{decision: DecisionUpToDate, expected: true},
+		{decision: DecisionIgnored, expected: true},
+		{decision: DecisionOutOfSync, expected: false},
+		{decision: DecisionFailed, expected: false},
+		{decision: DecisionStale, expected: true},
+		{decision: DecisionStale, recheckStale: true, expected: false},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(fmt.Sprintf("%s (recheck stale: %v)", testcase.decision, testcase.recheckStale), func(t *testing.T) {
+			state := NewState()
+			state.record(testOrg, repo.Name, branch, testTeams(), testcase.decision)
+
+			if unchanged := state.unchanged(testOrg, repo, testTeams(), testcase.recheckStale); unchanged != testcase.expected {
+				t.Errorf("Expected unchanged=%v, got %v.", testcase.expected, unchanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,576 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package syncer implements the synchronization of GitHub teams into
+// OWNERS_ALIASES files.
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/git"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const DefaultFileHeader = `
+# This file was automatically generated by prow-aliases-syncer. DO NOT EDIT.
+`
+
+var DefaultPRBody = strings.ReplaceAll(`
+This pull request updates the {{ .Filename }} file{{ if gt (len .Filenames) 1 }}s{{ end }} based on the GitHub team associations.
+
+**Release Notes:**
+§§§release-note
+NONE
+§§§
+`, "§", "`")
+
+// DefaultHeadBranch is the template for the branch that pull requests are
+// created from. It must result in the same name for every run, as it is used
+// to find existing pull requests.
+const DefaultHeadBranch = `update-{{ .BaseBranch | replace "/" "-" }}-owners`
+
+// DefaultCommitMessage is the template for the commit message.
+const DefaultCommitMessage = `{{ if and (ne .BaseBranch "main") (ne .BaseBranch "master") }}[{{ .BaseBranch }}] {{ end }}Synchronize {{ .Filename }} file{{ if gt (len .Filenames) 1 }}s{{ end }} with Github teams`
+
+// DefaultTitle is the template for the pull request title.
+const DefaultTitle = DefaultCommitMessage
+
+// DefaultPeekDepth is the number of commits to retrieve per branch to check
+// for the most recent commit.
+const DefaultPeekDepth = 20
+
+// Clients bundles the clients used to talk to GitHub.
+type Clients struct {
+	Teams        github.TeamLister
+	Repositories github.RepositoryLister
+	PullRequests github.PullRequestClient
+	Git          git.RepositoryWriter
+	// Files is only required if AliasesPaths contains more than the
+	// default aliases file.
+	Files github.AliasesFileLister
+	// Activity is only required if ActivitySignals are configured.
+	Activity github.ActivityClient
+	// Protection is used to check whether branches can be updated directly
+	// if UpdateDirectly is set; if nil, all branches are pushed to directly.
+	Protection github.BranchProtectionChecker
+}
+
+type Options struct {
+	// Organizations to load teams from.
+	Organizations []string
+	// TargetOrganizations to update repositories in; defaults to Organizations.
+	TargetOrganizations []string
+	AliasSource         util.AliasSource
+	AliasNaming         util.AliasNaming
+	ConflictPolicy      util.ConflictPolicy
+
+	// Branches are glob expressions for the branches to update.
+	Branches []string
+	// BranchSelector decides which branches to update; defaults to a
+	// GlobSelector for the Branches.
+	BranchSelector BranchSelector
+	// IgnoredUsers are not considered when determining the most recent
+	// commit on a branch.
+	IgnoredUsers []string
+	// IgnoredCommitters, IgnoredEmails (of authors or committers) and
+	// IgnoredMessages ignore further commits, like IgnoredUsers.
+	IgnoredCommitters []string
+	IgnoredEmails     []string
+	IgnoredMessages   []*regexp.Regexp
+	// MaxAge is the maximum age of the most recent commit on a branch.
+	MaxAge time.Duration
+	// PeekDepth is the number of commits per branch to look at to find
+	// the most recent commit that is not ignored; defaults to
+	// DefaultPeekDepth.
+	PeekDepth int
+	// ActivitySignals are checked in order for branches whose most recent
+	// commit is older than MaxAge; if any of them is more recent, the branch
+	// is not considered stale.
+	ActivitySignals []ActivitySignal
+
+	// AliasesPaths are the paths or glob patterns (see prow.MatchPath) of
+	// the aliases files to update in each branch; defaults to the
+	// OWNERS_ALIASES file in the root directory.
+	AliasesPaths []string
+
+	// Config refines how aliases are generated from the teams; optional.
+	Config *config.Config
+
+	// Header is prepended to the generated aliases files.
+	Header string
+	// Body is the template for the pull request body; defaults to DefaultPRBody.
+	Body *template.Template
+	// HeadBranch is the template for the pull request branch; defaults to
+	// DefaultHeadBranch. HeadBranch is not set in its TemplateData.
+	HeadBranch *template.Template
+	// CommitMessage is the template for the commit message; defaults to
+	// DefaultCommitMessage.
+	CommitMessage *template.Template
+	// Title is the template for the pull request title; defaults to
+	// DefaultTitle.
+	Title *template.Template
+
+	DryRun         bool
+	UpdateDirectly bool
+	Strict         bool
+	Keep           bool
+	// SignedCommits is set if the git client signs commits, so that rulesets
+	// requiring signed commits do not prevent direct updates.
+	SignedCommits bool
+
+	// Limits protect against mass changes; if exceeded, the run is aborted
+	// with a LimitError before anything is written.
+	Limits Limits
+	// IgnoreLimits only logs violations of the Limits instead of aborting.
+	IgnoreLimits bool
+
+	// State is used to skip unchanged repositories in full runs; it is
+	// updated during each full run. If nil, all repositories are scanned.
+	State *State
+	// Full ignores the State and scans all repositories, but still updates
+	// the State.
+	Full bool
+
+	// OnEvent is called for every decision the syncer makes.
+	OnEvent func(Event)
+	// OnPhase is called after each phase of a run with its duration.
+	OnPhase func(Phase, time.Duration)
+
+	// unresolved are computed aliases that could not be resolved in the
+	// current run; their existing members are kept.
+	unresolved []string
+}
+
+// Scope restricts a synchronization run. The zero value means a full run.
+type Scope struct {
+	// Teams restricts the update to the aliases with these names; all other
+	// aliases are left untouched.
+	Teams []string
+
+	// Organization and Repository restrict the run to a single repository,
+	// Branch additionally to a single branch.
+	Organization string
+	Repository   string
+	Branch       string
+
+	// SkipActivityCheck synchronizes the branch even if it is stale, e.g.
+	// because a user explicitly requested it to be synchronized. The branch
+	// selector still applies.
+	SkipActivityCheck bool
+}
+
+func (s Scope) IsFull() bool {
+	return len(s.Teams) == 0 && s.Repository == ""
+}
+
+type TemplateData struct {
+	// Filename is the path of the updated aliases file; if several files
+	// are updated, it lists all Filenames, separated by commas.
+	Filename   string
+	Filenames  []string
+	BaseBranch string
+	HeadBranch string
+	Org        string
+	Repo       string
+}
+
+// NewTemplate parses a template for the body, head branch, commit message or
+// title. In addition to the standard functions, templates can use
+// `replace OLD NEW STRING`.
+func NewTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"replace": func(old, new, s string) string {
+			return strings.ReplaceAll(s, old, new)
+		},
+	}).Parse(text)
+}
+
+func renderTemplate(tpl *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+type Syncer struct {
+	log        logrus.FieldLogger
+	clients    Clients
+	opts       Options
+	configHash string
+}
+
+func New(log logrus.FieldLogger, clients Clients, opts Options) (*Syncer, error) {
+	if len(opts.Organizations) == 0 {
+		return nil, errors.New("no organizations given")
+	}
+
+	if opts.BranchSelector == nil {
+		if len(opts.Branches) == 0 {
+			return nil, errors.New("no branches given")
+		}
+
+		opts.BranchSelector = GlobSelector(opts.Branches...)
+	}
+
+	if len(opts.TargetOrganizations) == 0 {
+		opts.TargetOrganizations = opts.Organizations
+	}
+
+	if opts.AliasSource == "" {
+		opts.AliasSource = util.AliasSourceName
+	}
+
+	if opts.AliasNaming == "" {
+		opts.AliasNaming = util.AliasNamingPlain
+	}
+
+	if opts.ConflictPolicy == "" {
+		opts.ConflictPolicy = util.ConflictError
+	}
+
+	if len(opts.AliasesPaths) == 0 {
+		opts.AliasesPaths = []string{prow.OwnersAliasesFilename}
+	}
+
+	if discoverFiles(opts) && clients.Files == nil {
+		return nil, errors.New("aliases paths other than the default require a file lister")
+	}
+
+	if opts.PeekDepth <= 0 {
+		opts.PeekDepth = DefaultPeekDepth
+	}
+
+	if len(opts.ActivitySignals) > 0 && clients.Activity == nil {
+		return nil, errors.New("activity signals require an activity client")
+	}
+
+	if opts.Body == nil {
+		opts.Body = template.Must(NewTemplate("body", DefaultPRBody))
+	}
+
+	if opts.HeadBranch == nil {
+		opts.HeadBranch = template.Must(NewTemplate("head-branch", DefaultHeadBranch))
+	}
+
+	if opts.CommitMessage == nil {
+		opts.CommitMessage = template.Must(NewTemplate("commit-message", DefaultCommitMessage))
+	}
+
+	if opts.Title == nil {
+		opts.Title = template.Must(NewTemplate("title", DefaultTitle))
+	}
+
+	return &Syncer{
+		log:        log,
+		clients:    clients,
+		opts:       opts,
+		configHash: configHash(opts),
+	}, nil
+}
+
+// discoverFiles returns true if the aliases files have to be discovered in
+// each branch, because the OWNERS_ALIASES file in the root directory (which
+// is always fetched with the branches) is not the only one.
+func discoverFiles(opts Options) bool {
+	return len(opts.AliasesPaths) != 1 || opts.AliasesPaths[0] != prow.OwnersAliasesFilename
+}
+
+// Run performs a full synchronization. Failures for individual branches are
+// not returned as an error, but are part of the result.
+func (s *Syncer) Run(ctx context.Context) (*Result, error) {
+	return s.RunScoped(ctx, Scope{})
+}
+
+// RunScoped performs a synchronization limited to the given scope.
+func (s *Syncer) RunScoped(ctx context.Context, scope Scope) (*Result, error) {
+	return s.run(ctx, scope, s.opts, func(log logrus.FieldLogger, opts Options, result *Result, org string, _ []github.Repository, todo []github.Repository) error {
+		return s.processTasks(ctx, log, opts, result, org, todo, false)
+	})
+}
+
+// processFunc handles the branches that are out of sync in an organization;
+// repos are all repositories as they were listed, todo contains only the
+// branches to update, with their new aliases file.
+type processFunc func(log logrus.FieldLogger, opts Options, result *Result, org string, repos []github.Repository, todo []github.Repository) error
+
+func (s *Syncer) run(ctx context.Context, scope Scope, opts Options, process processFunc) (*Result, error) {
+	result := &Result{}
+
+	started := time.Now()
+
+	teams, err := s.getTeams()
+	if err != nil {
+		return result, err
+	}
+
+	s.observePhase(PhaseTeams, started)
+
+	if reason := checkTeamLimits(opts.Limits, teams); reason != "" {
+		if !opts.IgnoreLimits {
+			return result, &LimitError{Violations: []LimitViolation{{Reason: reason}}}
+		}
+
+		s.log.WithField("violation", reason).Warn("Safety limit exceeded, ignoring.")
+	}
+
+	if opts.Config != nil {
+		var failed map[string]error
+
+		teams, failed = util.ResolveComputedAliases(teams, opts.Config.Aliases)
+		for _, name := range sets.List(sets.KeySet(failed)) {
+			s.log.WithField("alias", name).WithError(failed[name]).Warn("Failed to resolve computed alias, keeping its current members.")
+			opts.unresolved = append(opts.unresolved, name)
+		}
+	}
+
+	if !scope.IsFull() {
+		// a scoped run only sees a part of the organizations
+		opts.State = nil
+	}
+
+	if opts.State != nil {
+		opts.State.reset(s.configHash)
+	}
+
+	if len(scope.Teams) > 0 {
+		names := scope.Teams
+
+		// computed aliases change along with their teams
+		if opts.Config != nil {
+			names = append(slices.Clone(names), opts.Config.Dependents(names)...)
+		}
+
+		teams = filterTeams(teams, names)
+
+		// keep all other aliases as they are
+		opts.Keep = true
+	}
+
+	// determine the changes in all organizations first, so that the limits
+	// can be checked before anything is written
+	jobs := []orgJobs{}
+	jobsDurations := []time.Duration{}
+
+	for _, targetOrg := range s.opts.TargetOrganizations {
+		if scope.Organization != "" && scope.Organization != targetOrg {
+			continue
+		}
+
+		tlog := s.log.WithField("target", targetOrg)
+
+		started := time.Now()
+
+		repos, unchanged, err := s.listRepositories(tlog, opts, targetOrg, scope, teams)
+		if err != nil {
+			return result, err
+		}
+
+		s.observePhase(PhaseRepositories, started)
+		result.Repositories += len(repos)
+
+		for _, repo := range unchanged {
+			for _, branch := range repo.Branches {
+				s.emit(result, Event{
+					Organization: targetOrg,
+					Repository:   repo.Name,
+					Branch:       branch.Name,
+					Decision:     DecisionUnchanged,
+				})
+			}
+		}
+
+		started = time.Now()
+
+		todo, err := s.createJobs(ctx, tlog, opts, scope, result, targetOrg, repos, teams)
+		if err != nil {
+			return result, fmt.Errorf("failed to determine tasks: %w", err)
+		}
+
+		var unwritten sets.Set[string]
+		if opts.Limits.MaxWrites > 0 {
+			unwritten, err = s.unwrittenBranches(opts, targetOrg, todo)
+			if err != nil {
+				return result, fmt.Errorf("failed to check for existing pull requests: %w", err)
+			}
+		}
+
+		jobs = append(jobs, orgJobs{
+			org:       targetOrg,
+			repos:     repos,
+			todo:      todo,
+			unwritten: unwritten,
+		})
+		jobsDurations = append(jobsDurations, time.Since(started))
+	}
+
+	if violations := checkLimits(opts.Limits, jobs); len(violations) > 0 {
+		if !opts.IgnoreLimits {
+			return result, &LimitError{Violations: violations}
+		}
+
+		for _, v := range violations {
+			s.log.WithField("violation", v.String()).Warn("Safety limit exceeded, ignoring.")
+		}
+	}
+
+	for i, j := range jobs {
+		// include the time it took to determine the changes
+		started := time.Now().Add(-jobsDurations[i])
+
+		if len(j.todo) > 0 {
+			if err := process(s.log.WithField("target", j.org), opts, result, j.org, j.repos, j.todo); err != nil {
+				return result, fmt.Errorf("failed to process: %w", err)
+			}
+		}
+
+		s.observePhase(PhaseProcessing, started)
+	}
+
+	return result, nil
+}
+
+// listRepositories returns the repositories that need to be checked and the
+// repositories that are unchanged according to the state.
+func (s *Syncer) listRepositories(log logrus.FieldLogger, opts Options, org string, scope Scope, teams []github.Team) ([]github.Repository, []github.Repository, error) {
+	if scope.Repository == "" {
+		if opts.State != nil && !opts.Full && opts.State.hasOrganization(org) {
+			return s.listChangedRepositories(log, opts, org, teams)
+		}
+
+		// list all repos with all branches and the OWNERS_ALIASES file in each of them
+		log.Info("Listing repositories and branches…")
+
+		repos, err := s.clients.Repositories.GetRepositoriesAndBranches(org, activityOptions(s.opts))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		log.Infof("Found %d repositories.", len(repos))
+
+		if opts.State != nil {
+			opts.State.forget(org, sets.New[string]())
+		}
+
+		return repos, nil, nil
+	}
+
+	log.WithField("repo", scope.Repository).Info("Fetching repository…")
+
+	repo, err := s.clients.Repositories.GetRepository(org, scope.Repository, activityOptions(s.opts))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if repo == nil {
+		return nil, nil, fmt.Errorf("repository %s/%s does not exist", org, scope.Repository)
+	}
+
+	// branch scopes are applied in createJobs, as the repository settings
+	// are read from the default branch
+
+	return []github.Repository{*repo}, nil, nil
+}
+
+// listChangedRepositories only lists the branch heads of all repositories
+// and then fetches the repositories that changed since the previous run.
+func (s *Syncer) listChangedRepositories(log logrus.FieldLogger, opts Options, org string, teams []github.Team) ([]github.Repository, []github.Repository, error) {
+	log.Info("Listing branch heads…")
+
+	heads, err := s.clients.Repositories.GetBranchHeads(org)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := []github.Repository{}
+	unchanged := []github.Repository{}
+	unchangedNames := sets.New[string]()
+
+	for _, head := range heads {
+		if opts.State.unchanged(org, head, teams, len(opts.ActivitySignals) > 0) {
+			unchanged = append(unchanged, head)
+			unchangedNames.Insert(head.Name)
+			continue
+		}
+
+		repo, err := s.clients.Repositories.GetRepository(org, head.Name, activityOptions(s.opts))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the repository was deleted in the meantime
+		if repo == nil {
+			continue
+		}
+
+		changed = append(changed, *repo)
+	}
+
+	opts.State.forget(org, unchangedNames)
+
+	log.Infof("Found %d repositories, %d unchanged since the previous run.", len(heads), len(unchanged))
+
+	return changed, unchanged, nil
+}
+
+func filterTeams(teams []github.Team, names []string) []github.Team {
+	result := []github.Team{}
+
+	for _, team := range teams {
+		if slices.Contains(names, team.Slug) {
+			result = append(result, team)
+		}
+	}
+
+	return result
+}
+
+// getTeams lists all teams and their members in all source organizations.
+func (s *Syncer) getTeams() ([]github.Team, error) {
+	orgTeams := []util.OrgTeams{}
+
+	for _, org := range s.opts.Organizations {
+		olog := s.log.WithField("source", org)
+		olog.Info("Listing teams…")
+
+		teams, err := s.clients.Teams.GetTeams(org)
+		if err != nil {
+			return nil, err
+		}
+
+		olog.Infof("Found %d teams.", len(teams))
+
+		for _, team := range teams {
+			olog.WithField("team", team.Slug).WithField("members", team.Members).Debug("Found team.")
+		}
+
+		orgTeams = append(orgTeams, util.OrgTeams{
+			Organization: org,
+			Teams:        teams,
+		})
+	}
+
+	teams, err := util.CombineTeams(orgTeams, s.opts.AliasSource, s.opts.AliasNaming, s.opts.ConflictPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to combine teams: %w", err)
+	}
+
+	return teams, nil
+}
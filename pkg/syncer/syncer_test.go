@@ -0,0 +1,683 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+const (
+	testOrg = "testorg"
+
+	upToDateAliases = `aliases:
+  sig-a:
+    - alice
+    - bob
+`
+
+	outdatedAliases = `aliases:
+  sig-a:
+    - alice
+`
+)
+
+func testTeams() []github.Team {
+	return []github.Team{
+		{Slug: "sig-a", Members: []string{"alice", "bob"}},
+	}
+}
+
+func testOptions() Options {
+	return Options{
+		Organizations: []string{testOrg},
+		Branches:      []string{"main", "release-*"},
+		MaxAge:        24 * time.Hour,
+		Body:          template.Must(template.New("body").Parse("Updates {{ .Filename }} in {{ .Org }}/{{ .Repo }}.")),
+	}
+}
+
+func newTestSyncer(t *testing.T, clients Clients, opts Options) *Syncer {
+	s, err := New(fake.Logger(), clients, opts)
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+
+	return s
+}
+
+func TestCreateJobs(t *testing.T) {
+	recent := time.Now().Add(-time.Hour)
+	stale := time.Now().Add(-48 * time.Hour)
+
+	testcases := []struct {
+		name     string
+		branches []github.Branch
+		strict   bool
+		expected []string
+		events   []Decision
+	}{
+		{
+			name: "up-to-date branch is skipped",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: upToDateAliases},
+			},
+			expected: nil,
+			events:   []Decision{DecisionUpToDate},
+		},
+		{
+			name: "outdated branch is updated",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: outdatedAliases},
+			},
+			expected: []string{"main"},
+			events:   []Decision{DecisionOutOfSync},
+		},
+		{
+			name: "branches not matching the filter are ignored",
+			branches: []github.Branch{
+				{Name: "feature", MostRecentCommit: recent, Aliases: outdatedAliases},
+				{Name: "release-1.0", MostRecentCommit: recent, Aliases: outdatedAliases},
+			},
+			expected: []string{"release-1.0"},
+			events:   []Decision{DecisionIgnored, DecisionOutOfSync},
+		},
+		{
+			name: "stale branches are ignored",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: stale, Aliases: outdatedAliases},
+			},
+			expected: nil,
+			events:   []Decision{DecisionStale},
+		},
+		{
+			name: "branches without aliases file are ignored",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent},
+			},
+			expected: nil,
+			events:   []Decision{DecisionNoAliasesFile},
+		},
+		{
+			name: "invalid aliases files are ignored",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: "{invalid"},
+			},
+			expected: nil,
+			events:   []Decision{DecisionInvalidAliasesFile},
+		},
+		{
+			name: "strict mode detects formatting changes",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: "aliases:\n  sig-a: [bob, alice]\n"},
+			},
+			strict:   true,
+			expected: []string{"main"},
+			events:   []Decision{DecisionOutOfSync},
+		},
+		{
+			name: "repositories can opt out",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: outdatedAliases, Settings: "ignore: true"},
+				{Name: "release-1.0", MostRecentCommit: recent, Aliases: outdatedAliases},
+			},
+			expected: nil,
+			events:   []Decision{DecisionOptedOut, DecisionOptedOut},
+		},
+		{
+			name: "default branch can exclude other branches",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: outdatedAliases, Settings: "ignoreBranches: [release-*]"},
+				{Name: "release-1.0", MostRecentCommit: recent, Aliases: outdatedAliases},
+			},
+			expected: []string{"main"},
+			events:   []Decision{DecisionOutOfSync, DecisionOptedOut},
+		},
+		{
+			name: "branches can opt out on their own",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: outdatedAliases},
+				{Name: "release-1.0", MostRecentCommit: recent, Aliases: outdatedAliases, Settings: "ignore: true"},
+			},
+			expected: []string{"main"},
+			events:   []Decision{DecisionOutOfSync, DecisionOptedOut},
+		},
+		{
+			name: "invalid settings are reported",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: outdatedAliases, Settings: "unknown: true"},
+			},
+			expected: nil,
+			events:   []Decision{DecisionFailed},
+		},
+		{
+			name: "settings can exclude aliases",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: outdatedAliases, Settings: "excludeAliases: [sig-a]"},
+			},
+			expected: nil,
+			events:   []Decision{DecisionUpToDate},
+		},
+		{
+			name: "settings can override strict mode",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: "aliases:\n  sig-a: [bob, alice]\n", Settings: "strict: false"},
+			},
+			strict:   true,
+			expected: nil,
+			events:   []Decision{DecisionUpToDate},
+		},
+		{
+			name: "aliases files can opt out with a marker comment",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: "# prow-aliases-syncer: ignore\n" + outdatedAliases},
+			},
+			expected: nil,
+			events:   []Decision{DecisionOptedOut},
+		},
+		{
+			name: "marker comments can exclude aliases",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: "# prow-aliases-syncer: exclude=sig-a\n" + outdatedAliases},
+			},
+			expected: nil,
+			events:   []Decision{DecisionUpToDate},
+		},
+		{
+			name: "unknown markers are invalid",
+			branches: []github.Branch{
+				{Name: "main", MostRecentCommit: recent, Aliases: "# prow-aliases-syncer: yolo\n" + outdatedAliases},
+			},
+			expected: nil,
+			events:   []Decision{DecisionInvalidAliasesFile},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			var events []Decision
+
+			opt := testOptions()
+			opt.Strict = testcase.strict
+			opt.OnEvent = func(e Event) {
+				events = append(events, e.Decision)
+			}
+
+			s := newTestSyncer(t, Clients{}, opt)
+
+			repos := []github.Repository{{
+				ID:            "repo-id",
+				Name:          "repo",
+				DefaultBranch: "main",
+				Branches:      testcase.branches,
+			}}
+
+			todo, err := s.createJobs(context.Background(), fake.Logger(), s.opts, Scope{}, &Result{}, testOrg, repos, testTeams())
+			if err != nil {
+				t.Fatalf("Failed to create jobs: %v", err)
+			}
+
+			var branches []string
+			for _, repo := range todo {
+				for _, branch := range repo.Branches {
+					branches = append(branches, branch.Name)
+				}
+			}
+
+			if diff := deep.Equal(branches, testcase.expected); diff != nil {
+				t.Fatalf("not equal: %v", diff)
+			}
+
+			if diff := deep.Equal(events, testcase.events); diff != nil {
+				t.Fatalf("events not equal: %v", diff)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	testcases := []struct {
+		name             string
+		updateDirectly   bool
+		protected        bool
+		dryRun           bool
+		existingPRs      []fake.PullRequest
+		expectedPRs      []fake.PullRequest
+		expectedBranches []string
+		expectedDecision Decision
+	}{
+		{
+			name: "creates pull request",
+			expectedPRs: []fake.PullRequest{{
+				Org:    testOrg,
+				Repo:   "repo",
+				Number: 1,
+				Base:   "main",
+				Head:   "update-main-owners",
+				Title:  "Synchronize OWNERS_ALIASES file with Github teams",
+				Body:   "Updates OWNERS_ALIASES in testorg/repo.",
+			}},
+			expectedBranches: []string{"update-main-owners"},
+			expectedDecision: DecisionPullRequestCreated,
+		},
+		{
+			name: "does not create a second pull request",
+			existingPRs: []fake.PullRequest{{
+				Org:    testOrg,
+				Repo:   "repo",
+				Number: 7,
+				Base:   "main",
+				Head:   "update-main-owners",
+			}},
+			expectedPRs: []fake.PullRequest{{
+				Org:    testOrg,
+				Repo:   "repo",
+				Number: 7,
+				Base:   "main",
+				Head:   "update-main-owners",
+			}},
+			expectedBranches: nil,
+			expectedDecision: DecisionPullRequestExists,
+		},
+		{
+			name:             "updates branch directly",
+			updateDirectly:   true,
+			expectedPRs:      []fake.PullRequest{},
+			expectedBranches: []string{"main"},
+			expectedDecision: DecisionUpdated,
+		},
+		{
+			name:           "falls back to pull request for protected branches",
+			updateDirectly: true,
+			protected:      true,
+			expectedPRs: []fake.PullRequest{{
+				Org:    testOrg,
+				Repo:   "repo",
+				Number: 1,
+				Base:   "main",
+				Head:   "update-main-owners",
+				Title:  "Synchronize OWNERS_ALIASES file with Github teams",
+				Body:   "Updates OWNERS_ALIASES in testorg/repo.",
+			}},
+			expectedBranches: []string{"update-main-owners"},
+			expectedDecision: DecisionPullRequestCreated,
+		},
+		{
+			name:             "dry run does not push",
+			dryRun:           true,
+			expectedPRs:      []fake.PullRequest{},
+			expectedBranches: nil,
+			expectedDecision: DecisionDryRun,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			opt := testOptions()
+			opt.UpdateDirectly = testcase.updateDirectly
+			opt.DryRun = testcase.dryRun
+
+			gh := fake.NewGitHub()
+			gh.Teams[testOrg] = testTeams()
+			gh.Repositories[testOrg] = []github.Repository{{
+				ID:   "repo-id",
+				Name: "repo",
+				Branches: []github.Branch{
+					{Name: "main", MostRecentCommit: time.Now(), Aliases: outdatedAliases},
+				},
+			}}
+			gh.PullRequests = append(gh.PullRequests, testcase.existingPRs...)
+
+			if testcase.protected {
+				gh.ProtectedBranches[testOrg+"/repo/main"] = "branch is protected"
+			}
+
+			gitter := fake.NewGit()
+			gitter.AddRemote(testOrg, "repo", map[string]fake.Files{
+				"main": {prow.OwnersAliasesFilename: outdatedAliases},
+			})
+
+			clients := Clients{
+				Teams:        gh,
+				Repositories: gh,
+				PullRequests: gh,
+				Git:          gitter,
+				Protection:   gh,
+			}
+
+			result, err := newTestSyncer(t, clients, opt).Run(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to synchronize: %v", err)
+			}
+
+			if len(result.Branches) != 1 || result.Branches[0].Decision != testcase.expectedDecision {
+				t.Errorf("Expected a single %q decision, got %+v.", testcase.expectedDecision, result.Branches)
+			}
+
+			if fallback := result.Branches[0].Fallback; fallback != testcase.protected {
+				t.Errorf("Expected fallback to be %v, got %v.", testcase.protected, fallback)
+			}
+
+			if diff := deep.Equal(gh.PullRequests, testcase.expectedPRs); diff != nil {
+				t.Errorf("pull requests not equal: %v", diff)
+			}
+
+			var pushed []string
+			for _, push := range gitter.Pushes {
+				pushed = append(pushed, push.Branch)
+
+				if push.Files[prow.OwnersAliasesFilename] != upToDateAliases {
+					t.Errorf("Pushed branch %q has unexpected aliases:\n%s", push.Branch, push.Files[prow.OwnersAliasesFilename])
+				}
+			}
+
+			if diff := deep.Equal(pushed, testcase.expectedBranches); diff != nil {
+				t.Errorf("pushed branches not equal: %v", diff)
+			}
+
+			if result.Repositories != 1 || result.Pushes != len(testcase.expectedBranches) {
+				t.Errorf("Expected 1 repository and %d pushes, got %d and %d.", len(testcase.expectedBranches), result.Repositories, result.Pushes)
+			}
+		})
+	}
+}
+
+func TestRunScopedRespectsRepositorySettings(t *testing.T) {
+	testcases := []struct {
+		name     string
+		settings string
+	}{
+		{
+			name:     "repository opted out",
+			settings: "ignore: true\n",
+		},
+		{
+			name:     "branch ignored by repository settings",
+			settings: "ignoreBranches: [\"release-*\"]\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			gh := fake.NewGitHub()
+			gh.Teams[testOrg] = testTeams()
+			gh.Repositories[testOrg] = []github.Repository{{
+				ID:            "repo-id",
+				Name:          "repo",
+				DefaultBranch: "main",
+				Branches: []github.Branch{
+					{Name: "main", MostRecentCommit: time.Now(), Aliases: outdatedAliases, Settings: testcase.settings},
+					{Name: "release-1.0", MostRecentCommit: time.Now(), Aliases: outdatedAliases},
+				},
+			}}
+
+			gitter := fake.NewGit()
+
+			clients := Clients{
+				Teams:        gh,
+				Repositories: gh,
+				PullRequests: gh,
+				Git:          gitter,
+				Protection:   gh,
+			}
+
+			scope := Scope{
+				Organization: testOrg,
+				Repository:   "repo",
+				Branch:       "release-1.0",
+			}
+
+			result, err := newTestSyncer(t, clients, testOptions()).RunScoped(context.Background(), scope)
+			if err != nil {
+				t.Fatalf("Failed to synchronize: %v", err)
+			}
+
+			if len(result.Branches) != 1 || result.Branches[0].Branch != "release-1.0" || result.Branches[0].Decision != DecisionOptedOut {
+				t.Errorf("Expected a single %q decision for release-1.0, got %+v.", DecisionOptedOut, result.Branches)
+			}
+
+			if len(gitter.Pushes) > 0 || len(gh.PullRequests) > 0 {
+				t.Errorf("Expected no changes, got %d pushes and %d pull requests.", len(gitter.Pushes), len(gh.PullRequests))
+			}
+		})
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	testcases := []struct {
+		branch          string
+		expectedHead    string
+		expectedMessage string
+	}{
+		{
+			branch:          "main",
+			expectedHead:    "update-main-owners",
+			expectedMessage: "Synchronize OWNERS_ALIASES file with Github teams",
+		},
+		{
+			branch:          "release/1.0",
+			expectedHead:    "update-release-1.0-owners",
+			expectedMessage: "[release/1.0] Synchronize OWNERS_ALIASES file with Github teams",
+		},
+	}
+
+	s := newTestSyncer(t, Clients{}, testOptions())
+
+	for _, testcase := range testcases {
+		t.Run(testcase.branch, func(t *testing.T) {
+			data := TemplateData{
+				Filename:   prow.OwnersAliasesFilename,
+				BaseBranch: testcase.branch,
+			}
+
+			head, err := renderTemplate(s.opts.HeadBranch, data)
+			if err != nil {
+				t.Fatalf("Failed to render head branch: %v", err)
+			}
+
+			if head != testcase.expectedHead {
+				t.Errorf("Expected head branch %q, got %q.", testcase.expectedHead, head)
+			}
+
+			message, err := renderTemplate(s.opts.CommitMessage, data)
+			if err != nil {
+				t.Fatalf("Failed to render commit message: %v", err)
+			}
+
+			if message != testcase.expectedMessage {
+				t.Errorf("Expected commit message %q, got %q.", testcase.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestCustomTemplates(t *testing.T) {
+	gh := fake.NewGitHub()
+	gh.Teams[testOrg] = testTeams()
+	gh.Repositories[testOrg] = []github.Repository{{
+		ID:   "repo-id",
+		Name: "repo",
+		Branches: []github.Branch{
+			{Name: "release-1.0", MostRecentCommit: time.Now(), Aliases: outdatedAliases},
+		},
+	}}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote(testOrg, "repo", map[string]fake.Files{
+		"release-1.0": {prow.OwnersAliasesFilename: outdatedAliases},
+	})
+
+	clients := Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+	}
+
+	opt := testOptions()
+	opt.HeadBranch = template.Must(NewTemplate("head-branch", "aliases/{{ .BaseBranch }}"))
+	opt.CommitMessage = template.Must(NewTemplate("commit-message", "chore: update {{ .Filename }}"))
+	opt.Title = template.Must(NewTemplate("title", "[{{ .BaseBranch }}] Update aliases from {{ .HeadBranch }}"))
+
+	for i := 0; i < 2; i++ {
+		if _, err := newTestSyncer(t, clients, opt).Run(context.Background()); err != nil {
+			t.Fatalf("Failed to synchronize: %v", err)
+		}
+	}
+
+	// the second run must find the pull request from the first run
+	expectedPRs := []fake.PullRequest{{
+		Org:    testOrg,
+		Repo:   "repo",
+		Number: 1,
+		Base:   "release-1.0",
+		Head:   "aliases/release-1.0",
+		Title:  "[release-1.0] Update aliases from aliases/release-1.0",
+		Body:   "Updates OWNERS_ALIASES in testorg/repo.",
+	}}
+
+	if diff := deep.Equal(gh.PullRequests, expectedPRs); diff != nil {
+		t.Errorf("pull requests not equal: %v", diff)
+	}
+
+	if len(gitter.Commits) != 1 || gitter.Commits[0].Message != "chore: update OWNERS_ALIASES" {
+		t.Errorf("Expected a single commit with a custom message, got %+v.", gitter.Commits)
+	}
+}
+
+func TestAliasesPaths(t *testing.T) {
+	gh := fake.NewGitHub()
+	gh.Teams[testOrg] = testTeams()
+	gh.Repositories[testOrg] = []github.Repository{{
+		ID:   "repo-id",
+		Name: "repo",
+		Branches: []github.Branch{{
+			Name:             "main",
+			HeadOID:          "abc",
+			MostRecentCommit: time.Now(),
+			Aliases:          outdatedAliases,
+			Files: map[string]string{
+				"docs/OWNERS_ALIASES":         outdatedAliases,
+				"charts/foo/OWNERS_ALIASES":   upToDateAliases,
+				"vendor/other/OWNERS_ALIASES": outdatedAliases,
+			},
+		}},
+	}}
+
+	files := fake.Files{
+		prow.OwnersAliasesFilename:    outdatedAliases,
+		"docs/OWNERS_ALIASES":         outdatedAliases,
+		"charts/foo/OWNERS_ALIASES":   upToDateAliases,
+		"vendor/other/OWNERS_ALIASES": outdatedAliases,
+	}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote(testOrg, "repo", map[string]fake.Files{"main": files})
+
+	clients := Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+		Files:        gh,
+	}
+
+	opt := testOptions()
+	opt.AliasesPaths = []string{prow.OwnersAliasesFilename, "docs/**/OWNERS_ALIASES", "charts/*/OWNERS_ALIASES"}
+
+	result, err := newTestSyncer(t, clients, opt).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to synchronize: %v", err)
+	}
+
+	if len(result.Branches) != 1 || result.Branches[0].Decision != DecisionPullRequestCreated {
+		t.Fatalf("Expected a single pull request, got %+v.", result.Branches)
+	}
+
+	// all changed files must be part of the same commit
+	if len(gitter.Commits) != 1 || len(gitter.Pushes) != 1 {
+		t.Fatalf("Expected a single commit and push, got %+v.", gitter.Commits)
+	}
+
+	expected := fake.Files{
+		prow.OwnersAliasesFilename:    upToDateAliases,
+		"docs/OWNERS_ALIASES":         upToDateAliases,
+		"charts/foo/OWNERS_ALIASES":   upToDateAliases,
+		"vendor/other/OWNERS_ALIASES": outdatedAliases,
+	}
+
+	if diff := deep.Equal(gitter.Pushes[0].Files, expected); diff != nil {
+		t.Errorf("pushed files not equal: %v", diff)
+	}
+
+	title := "Synchronize OWNERS_ALIASES, docs/OWNERS_ALIASES files with Github teams"
+	if len(gh.PullRequests) != 1 || gh.PullRequests[0].Title != title {
+		t.Errorf("Expected a single pull request titled %q, got %+v.", title, gh.PullRequests)
+	}
+}
+
+func TestTemplatesForSubdirectoryFiles(t *testing.T) {
+	gh := fake.NewGitHub()
+	gh.Teams[testOrg] = testTeams()
+	gh.Repositories[testOrg] = []github.Repository{{
+		ID:   "repo-id",
+		Name: "repo",
+		Branches: []github.Branch{{
+			Name:             "main",
+			HeadOID:          "abc",
+			MostRecentCommit: time.Now(),
+			Files: map[string]string{
+				prow.OwnersAliasesFilename: upToDateAliases,
+				"docs/OWNERS_ALIASES":      outdatedAliases,
+			},
+		}},
+	}}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote(testOrg, "repo", map[string]fake.Files{"main": {
+		prow.OwnersAliasesFilename: upToDateAliases,
+		"docs/OWNERS_ALIASES":      outdatedAliases,
+	}})
+
+	clients := Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+		Files:        gh,
+	}
+
+	opt := testOptions()
+	opt.AliasesPaths = []string{prow.OwnersAliasesFilename, "docs/OWNERS_ALIASES"}
+
+	if _, err := newTestSyncer(t, clients, opt).Run(context.Background()); err != nil {
+		t.Fatalf("Failed to synchronize: %v", err)
+	}
+
+	expectedPRs := []fake.PullRequest{{
+		Org:    testOrg,
+		Repo:   "repo",
+		Number: 1,
+		Base:   "main",
+		Head:   "update-main-owners",
+		Title:  "Synchronize docs/OWNERS_ALIASES file with Github teams",
+		Body:   "Updates docs/OWNERS_ALIASES in testorg/repo.",
+	}}
+
+	if diff := deep.Equal(gh.PullRequests, expectedPRs); diff != nil {
+		t.Errorf("pull requests not equal: %v", diff)
+	}
+
+	if len(gitter.Commits) != 1 || gitter.Commits[0].Message != expectedPRs[0].Title {
+		t.Errorf("Expected a single commit with the same message as the title, got %+v.", gitter.Commits)
+	}
+}
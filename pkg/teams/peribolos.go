@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package teams
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// PeribolosConfig contains the teams from one or more Peribolos org configs
+// (org.yaml), see https://docs.prow.k8s.io/docs/components/cli-tools/peribolos/.
+// Only the parts relevant for teams are parsed, all other settings are
+// ignored.
+type PeribolosConfig struct {
+	Orgs map[string]PeribolosOrg `yaml:"orgs"`
+}
+
+type PeribolosOrg struct {
+	Teams map[string]PeribolosTeam `yaml:"teams"`
+}
+
+type PeribolosTeam struct {
+	Maintainers []string                 `yaml:"maintainers"`
+	Members     []string                 `yaml:"members"`
+	Children    map[string]PeribolosTeam `yaml:"teams"`
+}
+
+var _ github.TeamLister = &PeribolosConfig{}
+
+// LoadPeribolosConfig reads and merges one or more Peribolos config files.
+// Large organizations commonly split their config into multiple files; each
+// team must only be defined once across all files.
+func LoadPeribolosConfig(filenames ...string) (*PeribolosConfig, error) {
+	config := &PeribolosConfig{
+		Orgs: map[string]PeribolosOrg{},
+	}
+
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := DecodePeribolosConfig(f)
+		f.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		if err := config.merge(parsed); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	return config, nil
+}
+
+func DecodePeribolosConfig(r io.Reader) (*PeribolosConfig, error) {
+	config := &PeribolosConfig{}
+
+	if err := yaml.NewDecoder(r).Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid Peribolos config: %w", err)
+	}
+
+	for orgName, org := range config.Orgs {
+		slugs := sets.New[string]()
+
+		if err := collectSlugs(org.Teams, slugs); err != nil {
+			return nil, fmt.Errorf("invalid Peribolos config for organization %q: %w", orgName, err)
+		}
+	}
+
+	return config, nil
+}
+
+func collectSlugs(teams map[string]PeribolosTeam, slugs sets.Set[string]) error {
+	for name, team := range teams {
+		slug := TeamSlug(name)
+		if slug == "" {
+			return fmt.Errorf("team name %q results in an empty slug", name)
+		}
+
+		if slugs.Has(slug) {
+			return fmt.Errorf("team %q is defined multiple times", slug)
+		}
+		slugs.Insert(slug)
+
+		if err := collectSlugs(team.Children, slugs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *PeribolosConfig) merge(other *PeribolosConfig) error {
+	for orgName, org := range other.Orgs {
+		existing, exists := c.Orgs[orgName]
+		if !exists {
+			c.Orgs[orgName] = org
+			continue
+		}
+
+		merged := PeribolosOrg{
+			Teams: map[string]PeribolosTeam{},
+		}
+
+		for name, team := range existing.Teams {
+			merged.Teams[name] = team
+		}
+
+		for name, team := range org.Teams {
+			merged.Teams[name] = team
+		}
+
+		if len(merged.Teams) != len(existing.Teams)+len(org.Teams) {
+			return fmt.Errorf("organization %q: teams are defined in multiple files", orgName)
+		}
+
+		if err := collectSlugs(merged.Teams, sets.New[string]()); err != nil {
+			return fmt.Errorf("organization %q: %w", orgName, err)
+		}
+
+		c.Orgs[orgName] = merged
+	}
+
+	return nil
+}
+
+var slugRegexp = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// TeamSlug returns the slug GitHub generates for a team name.
+func TeamSlug(name string) string {
+	slug := slugRegexp.ReplaceAllString(strings.ToLower(name), "-")
+
+	return strings.Trim(slug, "-")
+}
+
+// GetTeams returns the teams of an organization in the same form as the
+// GitHub client would. Like on GitHub, the members of nested teams are also
+// members of their parent teams.
+func (c *PeribolosConfig) GetTeams(org string) ([]github.Team, error) {
+	o, exists := c.Orgs[org]
+	if !exists {
+		return nil, fmt.Errorf("organization %q is not part of the Peribolos config", org)
+	}
+
+	result := []github.Team{}
+	convertPeribolosTeams(o.Teams, "", &result)
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Slug) < strings.ToLower(result[j].Slug)
+	})
+
+	return result, nil
+}
+
+// convertPeribolosTeams adds the teams and their children to the result
+// and returns the roles of all their direct and indirect members.
+func convertPeribolosTeams(teams map[string]PeribolosTeam, parent string, result *[]github.Team) map[string]github.TeamRole {
+	all := map[string]github.TeamRole{}
+
+	for name, t := range teams {
+		team := github.Team{
+			Slug:   TeamSlug(name),
+			Name:   name,
+			Parent: parent,
+			Roles:  map[string]github.TeamRole{},
+		}
+
+		// members inherited from child teams are regular members of the
+		// parent, unless they are explicitly listed with a different role
+		for login := range convertPeribolosTeams(t.Children, team.Slug, result) {
+			team.Roles[login] = github.TeamRoleMember
+		}
+
+		for _, login := range t.Members {
+			team.Roles[login] = github.TeamRoleMember
+		}
+
+		for _, login := range t.Maintainers {
+			team.Roles[login] = github.TeamRoleMaintainer
+		}
+
+		team.Members = sets.List(sets.KeySet(team.Roles))
+
+		for login, role := range team.Roles {
+			if all[login] != github.TeamRoleMaintainer {
+				all[login] = role
+			}
+		}
+
+		*result = append(*result, team)
+	}
+
+	return all
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package teams
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+const peribolosConfig = `
+orgs:
+  myorg:
+    name: My Org
+    admins: [root]
+    members: [alice, bob, carol, dave]
+    teams:
+      SIG Release:
+        description: SIG Release
+        privacy: closed
+        maintainers: [alice]
+        members: [bob]
+        teams:
+          Release Managers:
+            maintainers: [carol]
+            members: [alice]
+      docs:
+        members: [dave]
+        repos:
+          website: write
+  otherorg:
+    teams:
+      ops:
+        members: [erin]
+`
+
+func TestPeribolosConfig(t *testing.T) {
+	config, err := DecodePeribolosConfig(strings.NewReader(peribolosConfig))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	result, err := config.GetTeams("myorg")
+	if err != nil {
+		t.Fatalf("Failed to get teams: %v", err)
+	}
+
+	expected := []github.Team{
+		{
+			Slug:    "docs",
+			Name:    "docs",
+			Members: []string{"dave"},
+			Roles: map[string]github.TeamRole{
+				"dave": github.TeamRoleMember,
+			},
+		},
+		{
+			Slug:    "release-managers",
+			Name:    "Release Managers",
+			Parent:  "sig-release",
+			Members: []string{"alice", "carol"},
+			Roles: map[string]github.TeamRole{
+				"alice": github.TeamRoleMember,
+				"carol": github.TeamRoleMaintainer,
+			},
+		},
+		{
+			// members of child teams are members of the parent team as well
+			Slug:    "sig-release",
+			Name:    "SIG Release",
+			Members: []string{"alice", "bob", "carol"},
+			Roles: map[string]github.TeamRole{
+				"alice": github.TeamRoleMaintainer,
+				"bob":   github.TeamRoleMember,
+				"carol": github.TeamRoleMember,
+			},
+		},
+	}
+
+	if diff := deep.Equal(result, expected); diff != nil {
+		t.Fatalf("not equal: %v", diff)
+	}
+
+	if _, err := config.GetTeams("unknownorg"); err == nil {
+		t.Fatal("Expected an error for an unknown organization, but got none.")
+	}
+}
+
+func TestLoadPeribolosConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"org.yaml":   "orgs:\n  myorg:\n    teams:\n      sig-a:\n        members: [alice]\n",
+		"teams.yaml": "orgs:\n  myorg:\n    teams:\n      sig-b:\n        members: [bob]\n",
+		"dupe.yaml":  "orgs:\n  myorg:\n    teams:\n      SIG A:\n        members: [bob]\n",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	config, err := LoadPeribolosConfig(filepath.Join(dir, "org.yaml"), filepath.Join(dir, "teams.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	result, err := config.GetTeams("myorg")
+	if err != nil {
+		t.Fatalf("Failed to get teams: %v", err)
+	}
+
+	if len(result) != 2 || result[0].Slug != "sig-a" || result[1].Slug != "sig-b" {
+		t.Fatalf("Expected teams from both files, got %+v.", result)
+	}
+
+	// "SIG A" has the same slug as "sig-a"
+	if _, err := LoadPeribolosConfig(filepath.Join(dir, "org.yaml"), filepath.Join(dir, "dupe.yaml")); err == nil {
+		t.Fatal("Expected an error for a team defined in multiple files, but got none.")
+	}
+}
+
+func TestTeamSlug(t *testing.T) {
+	testcases := map[string]string{
+		"sig-a":            "sig-a",
+		"SIG Release":      "sig-release",
+		"Release Managers": "release-managers",
+		"  Foo / Bar  ":    "foo-bar",
+		"team_1":           "team_1",
+	}
+
+	for name, expected := range testcases {
+		if slug := TeamSlug(name); slug != expected {
+			t.Errorf("Expected slug %q for %q, got %q.", expected, name, slug)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package teams provides alternative sources for team data.
+package teams
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+// SnapshotVersion is the version of the snapshot file format.
+const SnapshotVersion = 1
+
+// Snapshot contains the teams of one or more organizations, e.g. as exported
+// by "prow-aliases-syncer teams export".
+type Snapshot struct {
+	Version       int            `yaml:"version"`
+	Organizations []Organization `yaml:"organizations"`
+}
+
+type Organization struct {
+	Name  string `yaml:"name"`
+	Teams []Team `yaml:"teams"`
+}
+
+type Team struct {
+	Slug    string   `yaml:"slug"`
+	Name    string   `yaml:"name,omitempty"`
+	Parent  string   `yaml:"parent,omitempty"`
+	Members []Member `yaml:"members"`
+}
+
+type Member struct {
+	Login string          `yaml:"login"`
+	Role  github.TeamRole `yaml:"role,omitempty"`
+}
+
+var _ github.TeamLister = &Snapshot{}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Version:       SnapshotVersion,
+		Organizations: []Organization{},
+	}
+}
+
+// LoadSnapshot reads and validates a snapshot file.
+func LoadSnapshot(filename string) (*Snapshot, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeSnapshot(f)
+}
+
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	if err := snapshot.validate(); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+func (s *Snapshot) validate() error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported version %d, expected %d", s.Version, SnapshotVersion)
+	}
+
+	orgs := map[string]struct{}{}
+
+	for _, org := range s.Organizations {
+		if org.Name == "" {
+			return errors.New("organization without name")
+		}
+
+		if _, exists := orgs[org.Name]; exists {
+			return fmt.Errorf("organization %q is listed multiple times", org.Name)
+		}
+		orgs[org.Name] = struct{}{}
+
+		slugs := map[string]struct{}{}
+
+		for _, team := range org.Teams {
+			if team.Slug == "" {
+				return fmt.Errorf("team without slug in organization %q", org.Name)
+			}
+
+			if _, exists := slugs[team.Slug]; exists {
+				return fmt.Errorf("team %q is listed multiple times in organization %q", team.Slug, org.Name)
+			}
+			slugs[team.Slug] = struct{}{}
+
+			for _, member := range team.Members {
+				switch member.Role {
+				case "", github.TeamRoleMember, github.TeamRoleMaintainer:
+				default:
+					return fmt.Errorf("invalid role %q for %q in team %s/%s", member.Role, member.Login, org.Name, team.Slug)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Add adds or replaces the teams of an organization.
+func (s *Snapshot) Add(org string, teams []github.Team) {
+	o := Organization{
+		Name:  org,
+		Teams: []Team{},
+	}
+
+	for _, team := range teams {
+		t := Team{
+			Slug:    team.Slug,
+			Name:    team.Name,
+			Parent:  team.Parent,
+			Members: []Member{},
+		}
+
+		for _, login := range team.Members {
+			t.Members = append(t.Members, Member{
+				Login: login,
+				Role:  team.Roles[login],
+			})
+		}
+
+		o.Teams = append(o.Teams, t)
+	}
+
+	for i, existing := range s.Organizations {
+		if existing.Name == org {
+			s.Organizations[i] = o
+			return
+		}
+	}
+
+	s.Organizations = append(s.Organizations, o)
+}
+
+// Encode writes the snapshot as YAML.
+func (s *Snapshot) Encode(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// GetTeams returns the teams of an organization in the same form as the
+// GitHub client would.
+func (s *Snapshot) GetTeams(org string) ([]github.Team, error) {
+	for _, o := range s.Organizations {
+		if o.Name != org {
+			continue
+		}
+
+		result := []github.Team{}
+
+		for _, t := range o.Teams {
+			team := github.Team{
+				Slug:    t.Slug,
+				Name:    t.Name,
+				Parent:  t.Parent,
+				Members: []string{},
+				Roles:   map[string]github.TeamRole{},
+			}
+
+			for _, member := range t.Members {
+				role := member.Role
+				if role == "" {
+					role = github.TeamRoleMember
+				}
+
+				team.Members = append(team.Members, member.Login)
+				team.Roles[member.Login] = role
+			}
+
+			result = append(result, team)
+		}
+
+		sort.Slice(result, func(i, j int) bool {
+			return strings.ToLower(result[i].Slug) < strings.ToLower(result[j].Slug)
+		})
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("organization %q is not part of the teams snapshot", org)
+}
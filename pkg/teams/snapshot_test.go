@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package teams
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+func TestSnapshotRoundtrip(t *testing.T) {
+	teams := []github.Team{
+		{
+			Slug:    "sig-a",
+			Name:    "SIG A",
+			Members: []string{"alice", "bob"},
+			Roles: map[string]github.TeamRole{
+				"alice": github.TeamRoleMaintainer,
+				"bob":   github.TeamRoleMember,
+			},
+		},
+		{
+			Slug:    "sig-a-reviewers",
+			Name:    "SIG A Reviewers",
+			Parent:  "sig-a",
+			Members: []string{},
+			Roles:   map[string]github.TeamRole{},
+		},
+	}
+
+	snapshot := NewSnapshot()
+	snapshot.Add("myorg", teams)
+
+	var buf bytes.Buffer
+	if err := snapshot.Encode(&buf); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	decoded, err := DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	result, err := decoded.GetTeams("myorg")
+	if err != nil {
+		t.Fatalf("Failed to get teams: %v", err)
+	}
+
+	if diff := deep.Equal(result, teams); diff != nil {
+		t.Fatalf("not equal: %v", diff)
+	}
+
+	if _, err := decoded.GetTeams("otherorg"); err == nil {
+		t.Fatal("Expected an error for an unknown organization, but got none.")
+	}
+}
+
+func TestDecodeSnapshot(t *testing.T) {
+	testcases := []struct {
+		input   string
+		invalid bool
+	}{
+		{
+			input: `
+version: 1
+organizations:
+  - name: myorg
+    teams:
+      - slug: sig-a
+        members:
+          - login: alice
+`,
+		},
+		{
+			input: `
+version: 2
+organizations: []
+`,
+			invalid: true,
+		},
+		{
+			input: `
+version: 1
+organizations:
+  - name: myorg
+    teams:
+      - slug: sig-a
+        members: []
+      - slug: sig-a
+        members: []
+`,
+			invalid: true,
+		},
+		{
+			input: `
+version: 1
+organizations:
+  - name: myorg
+    teams:
+      - slug: sig-a
+        members:
+          - login: alice
+            role: owner
+`,
+			invalid: true,
+		},
+		{
+			input: `
+version: 1
+organizations:
+  - name: myorg
+    teams:
+      - slug: sig-a
+        maintainers: [alice]
+`,
+			invalid: true,
+		},
+	}
+
+	for i, testcase := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			_, err := DecodeSnapshot(strings.NewReader(testcase.input))
+			if testcase.invalid {
+				if err == nil {
+					t.Fatal("Expected an error, but got none.")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -8,29 +8,164 @@ import (
 	"reflect"
 	"strings"
 
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
 	"go.xrstf.de/prow-aliases-syncer/pkg/github"
 	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
 )
 
-func Equal(oldFileContent string, teams []github.Team, strict bool, keep bool, fileHeader string) (bool, string, error) {
+type EqualOptions struct {
+	// Overrides are the extra and excluded members per alias.
+	Overrides map[string]config.Alias
+	// Excluded are aliases whose members are taken over from the old file
+	// as they are.
+	Excluded []string
+	// Strict compares the entire file instead of just the aliases.
+	Strict bool
+	// Keep retains aliases that do not belong to any team.
+	Keep bool
+	// Header is placed at the beginning of the file.
+	Header string
+}
+
+func Equal(oldFileContent string, teams []github.Team, opts EqualOptions) (bool, string, error) {
 	oldData, err := prow.FromString(oldFileContent)
 	if err != nil {
 		return false, "", fmt.Errorf("invalid aliases file: %w", err)
 	}
 
-	newData := BuildNewOwners(oldData, teams, keep)
+	newData := BuildNewOwners(oldData, teams, opts.Keep)
+	ApplyOverrides(newData, opts.Overrides)
+	keepComments(oldData, newData, opts.Overrides)
+	keepExcluded(oldData, newData, opts.Excluded)
 
-	encoded, err := newData.ToYAML(fileHeader)
+	encoded, err := newData.ToYAML(opts.Header)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to encode YAML: %w", err)
 	}
 
-	if strict {
+	if opts.Strict {
 		return strings.TrimSpace(oldFileContent) == strings.TrimSpace(encoded), encoded, nil
 	}
 
 	oldData.Sort()
 	newData.Sort()
 
-	return reflect.DeepEqual(oldData, newData), encoded, nil
+	// comments are compared as well, so that changed reasons for extra and
+	// excluded members reach the file
+	equal := reflect.DeepEqual(oldData.Aliases, newData.Aliases) &&
+		reflect.DeepEqual(nonEmpty(oldData.AliasComments), nonEmpty(newData.AliasComments)) &&
+		reflect.DeepEqual(nonEmptyMembers(oldData.MemberComments), nonEmptyMembers(newData.MemberComments))
+
+	return equal, encoded, nil
+}
+
+func nonEmpty(comments map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, comment := range comments {
+		if comment != "" {
+			result[key] = comment
+		}
+	}
+
+	return result
+}
+
+func nonEmptyMembers(comments map[string]map[string]string) map[string]map[string]string {
+	result := map[string]map[string]string{}
+	for alias, members := range comments {
+		if filtered := nonEmpty(members); len(filtered) > 0 {
+			result[alias] = filtered
+		}
+	}
+
+	return result
+}
+
+// keepComments takes over the hand-written comments of all aliases whose
+// comments are not generated from the overrides, so that they neither get
+// lost nor make the files differ. Member comments are only kept for members
+// that are still part of the alias.
+func keepComments(oldData, newData *prow.OwnersAliases, overrides map[string]config.Alias) {
+	for name, members := range newData.Aliases {
+		if _, exists := overrides[name]; exists {
+			continue
+		}
+
+		delete(newData.AliasComments, name)
+		delete(newData.MemberComments, name)
+
+		if comment, exists := oldData.AliasComments[name]; exists {
+			if newData.AliasComments == nil {
+				newData.AliasComments = map[string]string{}
+			}
+
+			newData.AliasComments[name] = comment
+		}
+
+		// logins are compared case-insensitively, but the new file might
+		// use a different casing
+		oldComments := map[string]string{}
+		for login, comment := range oldData.MemberComments[name] {
+			oldComments[strings.ToLower(login)] = comment
+		}
+
+		for _, member := range members {
+			comment, exists := oldComments[strings.ToLower(member)]
+			if !exists {
+				continue
+			}
+
+			if newData.MemberComments == nil {
+				newData.MemberComments = map[string]map[string]string{}
+			}
+
+			if newData.MemberComments[name] == nil {
+				newData.MemberComments[name] = map[string]string{}
+			}
+
+			newData.MemberComments[name][member] = comment
+		}
+	}
+}
+
+// copyComments replaces the comments of an alias with the ones from the
+// old file.
+func copyComments(oldData, newData *prow.OwnersAliases, name string) {
+	delete(newData.AliasComments, name)
+	delete(newData.MemberComments, name)
+
+	if comment, exists := oldData.AliasComments[name]; exists {
+		if newData.AliasComments == nil {
+			newData.AliasComments = map[string]string{}
+		}
+
+		newData.AliasComments[name] = comment
+	}
+
+	if comments, exists := oldData.MemberComments[name]; exists {
+		if newData.MemberComments == nil {
+			newData.MemberComments = map[string]map[string]string{}
+		}
+
+		newData.MemberComments[name] = comments
+	}
+}
+
+// keepExcluded replaces the generated members of excluded aliases with the
+// members (and comments) from the old file.
+func keepExcluded(oldData, newData *prow.OwnersAliases, excluded []string) {
+	for _, name := range excluded {
+		copyComments(oldData, newData, name)
+
+		members, exists := oldData.Aliases[name]
+		if !exists {
+			continue
+		}
+
+		if newData.Aliases == nil {
+			newData.Aliases = map[string][]string{}
+		}
+
+		newData.Aliases[name] = append([]string{}, members...)
+	}
 }
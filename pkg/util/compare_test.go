@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"testing"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+func TestEqualComparesComments(t *testing.T) {
+	teams := []github.Team{
+		{Slug: "sig-a", Members: []string{"alice"}},
+		{Slug: "sig-foo", Members: []string{"Bob", "carol"}},
+	}
+
+	overrides := map[string]config.Alias{
+		"sig-a": {
+			Extra: []config.Member{{Login: "bob", Reason: "emeritus lead"}},
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		content  string
+		keep     bool
+		expected bool
+	}{
+		{
+			name:     "same reason",
+			content:  "aliases:\n  sig-a:\n    - alice\n    - bob # emeritus lead\n",
+			expected: true,
+		},
+		{
+			name:     "changed reason",
+			content:  "aliases:\n  sig-a:\n    - alice\n    - bob # on leave\n",
+			expected: false,
+		},
+		{
+			name:     "missing reason",
+			content:  "aliases:\n  sig-a:\n    - alice\n    - bob\n",
+			expected: false,
+		},
+		{
+			name:     "comments on kept aliases",
+			content:  "aliases:\n  # maintained by hand\n  other:\n    - carol\n  sig-a:\n    - alice\n    - bob # emeritus lead\n",
+			keep:     true,
+			expected: true,
+		},
+		{
+			name:     "hand-written comments on team aliases",
+			content:  "aliases:\n  sig-a:\n    - alice\n    - bob # emeritus lead\n  # leads of sig foo\n  sig-foo:\n    - bob # tech lead\n    - carol\n",
+			expected: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			equal, _, err := Equal(testcase.content, teams, EqualOptions{
+				Overrides: overrides,
+				Keep:      testcase.keep,
+			})
+			if err != nil {
+				t.Fatalf("Failed to compare: %v", err)
+			}
+
+			if equal != testcase.expected {
+				t.Errorf("Expected equal=%v, got %v.", testcase.expected, equal)
+			}
+		})
+	}
+}
+
+func TestEqualKeepsHandWrittenComments(t *testing.T) {
+	teams := []github.Team{
+		{Slug: "sig-foo", Members: []string{"bob", "dave"}},
+	}
+
+	content := "aliases:\n  # leads of sig foo\n  sig-foo:\n    - bob # tech lead\n    - carol # emeritus\n"
+	expected := "aliases:\n  # leads of sig foo\n  sig-foo:\n    - bob # tech lead\n    - dave\n"
+
+	equal, encoded, err := Equal(content, teams, EqualOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+
+	if equal {
+		t.Error("Expected the file to be out of sync.")
+	}
+
+	if encoded != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, encoded)
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/logins"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ResolveComputedAliases evaluates all computed aliases and returns the
+// teams plus one additional team per computed alias, so that BuildNewOwners
+// can treat them like regular teams. The operands refer to the team slugs
+// after CombineTeams, i.e. to the alias names. The aliases must be free of
+// cycles, which config.Load ensures.
+//
+// Computed aliases that cannot be resolved, e.g. because an operand team was
+// deleted, are left out of the result and returned with their error instead,
+// so that a single broken alias does not prevent syncing all others. This
+// includes computed aliases that depend on a failed one.
+func ResolveComputedAliases(teams []github.Team, aliases map[string]config.Alias) ([]github.Team, map[string]error) {
+	members := map[string]sets.Set[string]{}
+	for _, team := range teams {
+		members[team.Slug] = logins.LowerSet(team.Members)
+	}
+
+	resolved := map[string]sets.Set[string]{}
+	failed := map[string]error{}
+
+	var resolve func(name string) (sets.Set[string], error)
+	resolve = func(name string) (sets.Set[string], error) {
+		if result, exists := resolved[name]; exists {
+			return result, nil
+		}
+
+		if err, exists := failed[name]; exists {
+			return nil, err
+		}
+
+		alias, exists := aliases[name]
+		if !exists || !alias.IsComputed() {
+			m, exists := members[name]
+			if !exists {
+				return nil, fmt.Errorf("unknown team or alias %q", name)
+			}
+
+			return m, nil
+		}
+
+		if _, exists := members[name]; exists {
+			failed[name] = fmt.Errorf("computed alias %q has the same name as a team", name)
+			return nil, failed[name]
+		}
+
+		var result sets.Set[string]
+
+		for i, operand := range alias.Operands() {
+			m, err := resolve(operand)
+			if err != nil {
+				failed[name] = fmt.Errorf("%s: %w", name, err)
+				return nil, failed[name]
+			}
+
+			switch {
+			case i == 0:
+				result = m.Clone()
+			case len(alias.Union) > 0:
+				result = result.Union(m)
+			case len(alias.Intersection) > 0:
+				result = result.Intersection(m)
+			default:
+				result = result.Difference(m)
+			}
+		}
+
+		resolved[name] = result
+
+		return result, nil
+	}
+
+	result := append([]github.Team{}, teams...)
+
+	for _, name := range sets.List(sets.KeySet(aliases)) {
+		if !aliases[name].IsComputed() {
+			continue
+		}
+
+		m, err := resolve(name)
+		if err != nil {
+			continue
+		}
+
+		team := github.Team{
+			Slug:    name,
+			Members: sets.List(m),
+			Roles:   map[string]github.TeamRole{},
+		}
+
+		for _, login := range team.Members {
+			team.Roles[login] = github.TeamRoleMember
+		}
+
+		result = append(result, team)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Slug) < strings.ToLower(result[j].Slug)
+	})
+
+	if len(failed) == 0 {
+		failed = nil
+	}
+
+	return result, failed
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestResolveComputedAliases(t *testing.T) {
+	teams := []github.Team{
+		{Slug: "sig-release-leads", Members: []string{"Alice", "bob"}},
+		{Slug: "release-engineering", Members: []string{"bob", "carol"}},
+		{Slug: "bots", Members: []string{"carol"}},
+	}
+
+	aliases := map[string]config.Alias{
+		"release-managers": {Union: []string{"sig-release-leads", "release-engineering"}},
+		"humans":           {Difference: []string{"release-managers", "bots"}},
+		"both":             {Intersection: []string{"sig-release-leads", "release-engineering"}},
+		"not-computed": {
+			Extra: []config.Member{{Login: "dave", Reason: "test"}},
+		},
+	}
+
+	result, failed := ResolveComputedAliases(teams, aliases)
+	if failed != nil {
+		t.Fatalf("Failed to resolve: %v", failed)
+	}
+
+	members := map[string][]string{}
+	for _, team := range result {
+		members[team.Slug] = team.Members
+	}
+
+	expected := map[string][]string{
+		"sig-release-leads":   {"Alice", "bob"},
+		"release-engineering": {"bob", "carol"},
+		"bots":                {"carol"},
+		"release-managers":    {"alice", "bob", "carol"},
+		"humans":              {"alice", "bob"},
+		"both":                {"bob"},
+	}
+
+	if diff := deep.Equal(members, expected); diff != nil {
+		t.Fatalf("members not equal: %v", diff)
+	}
+}
+
+func TestResolveComputedAliasesErrors(t *testing.T) {
+	teams := []github.Team{
+		{Slug: "sig-a", Members: []string{"alice"}},
+		{Slug: "sig-c", Members: []string{"carol"}},
+	}
+
+	aliases := map[string]config.Alias{
+		"valid":     {Union: []string{"sig-a"}},
+		"unknown":   {Union: []string{"sig-a", "sig-b"}},
+		"dependent": {Difference: []string{"unknown", "sig-a"}},
+		"sig-c":     {Union: []string{"valid"}},
+	}
+
+	result, failed := ResolveComputedAliases(teams, aliases)
+
+	slugs := []string{}
+	for _, team := range result {
+		slugs = append(slugs, team.Slug)
+	}
+
+	if diff := deep.Equal(slugs, []string{"sig-a", "sig-c", "valid"}); diff != nil {
+		t.Errorf("teams not equal: %v", diff)
+	}
+
+	failedNames := sets.List(sets.KeySet(failed))
+	if diff := deep.Equal(failedNames, []string{"dependent", "sig-c", "unknown"}); diff != nil {
+		t.Errorf("failed aliases not equal: %v", diff)
+	}
+}
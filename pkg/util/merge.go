@@ -4,8 +4,11 @@
 package util
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
 	"go.xrstf.de/prow-aliases-syncer/pkg/github"
 	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
 )
@@ -40,3 +43,67 @@ func BuildNewOwners(old *prow.OwnersAliases, teams []github.Team, keepUnknownTea
 
 	return result
 }
+
+// ApplyOverrides adds the extra members to and removes the excluded members
+// from the aliases that were built from the teams. The reasons are added as
+// comments, so that they are visible in the aliases file. Logins are
+// compared case-insensitively, but keep their casing.
+func ApplyOverrides(aliases *prow.OwnersAliases, overrides map[string]config.Alias) {
+	for name, members := range aliases.Aliases {
+		override, exists := overrides[name]
+		if !exists {
+			continue
+		}
+
+		// maps lowercase logins to their original casing
+		result := map[string]string{}
+		for _, m := range members {
+			if _, exists := result[strings.ToLower(m)]; !exists {
+				result[strings.ToLower(m)] = m
+			}
+		}
+
+		excluded := []string{}
+		for _, m := range override.Exclude {
+			delete(result, strings.ToLower(m.Login))
+			excluded = append(excluded, fmt.Sprintf("%s is excluded: %s", m.Login, m.Reason))
+		}
+
+		if len(excluded) > 0 {
+			if aliases.AliasComments == nil {
+				aliases.AliasComments = map[string]string{}
+			}
+
+			aliases.AliasComments[name] = strings.Join(excluded, "\n")
+		}
+
+		for _, m := range override.Extra {
+			login, exists := result[strings.ToLower(m.Login)]
+			if !exists {
+				login = m.Login
+				result[strings.ToLower(login)] = login
+			}
+
+			if aliases.MemberComments == nil {
+				aliases.MemberComments = map[string]map[string]string{}
+			}
+
+			if aliases.MemberComments[name] == nil {
+				aliases.MemberComments[name] = map[string]string{}
+			}
+
+			aliases.MemberComments[name][login] = m.Reason
+		}
+
+		logins := []string{}
+		for _, login := range result {
+			logins = append(logins, login)
+		}
+
+		sort.Slice(logins, func(i, j int) bool {
+			return strings.ToLower(logins[i]) < strings.ToLower(logins[j])
+		})
+
+		aliases.Aliases[name] = logins
+	}
+}
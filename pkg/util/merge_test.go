@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-test/deep"
 
+	"go.xrstf.de/prow-aliases-syncer/pkg/config"
 	"go.xrstf.de/prow-aliases-syncer/pkg/github"
 	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
 )
@@ -106,3 +107,43 @@ func TestBuildNewOwners(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyOverrides(t *testing.T) {
+	aliases := BuildNewOwners(&prow.OwnersAliases{
+		Aliases: map[string][]string{
+			"sig-a": {"alice"},
+			"sig-b": {"bob"},
+		},
+	}, []github.Team{
+		{Slug: "sig-a", Members: []string{"alice", "bob"}},
+		{Slug: "sig-b", Members: []string{"bob"}},
+	}, false)
+
+	ApplyOverrides(aliases, map[string]config.Alias{
+		"sig-a": {
+			Extra:   []config.Member{{Login: "Carol", Reason: "emeritus lead during handover"}, {Login: "ALICE", Reason: "lead"}},
+			Exclude: []config.Member{{Login: "bob", Reason: "on leave"}},
+		},
+		"unknown": {
+			Extra: []config.Member{{Login: "dave", Reason: "not part of the file"}},
+		},
+	})
+
+	encoded, err := aliases.ToYAML("")
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	expected := `aliases:
+  # bob is excluded: on leave
+  sig-a:
+    - alice # lead
+    - Carol # emeritus lead during handover
+  sig-b:
+    - bob
+`
+
+	if encoded != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, encoded)
+	}
+}
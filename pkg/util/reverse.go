@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"sort"
+	"strings"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// TeamChange describes how the members of a team need to change to match
+// the alias with the same name.
+type TeamChange struct {
+	Team string
+	// Members is the current number of immediate members in the team.
+	Members int
+	Add     []string
+	Remove  []string
+}
+
+// RemovedPercentage returns how many of the current members would be
+// removed, in percent.
+func (c TeamChange) RemovedPercentage() int {
+	if c.Members == 0 {
+		return 0
+	}
+
+	return len(c.Remove) * 100 / c.Members
+}
+
+// Empties returns true if the team would have no members left.
+func (c TeamChange) Empties() bool {
+	return c.Members > 0 && c.Members-len(c.Remove)+len(c.Add) == 0
+}
+
+// TeamMembers are the current members of a team.
+type TeamMembers struct {
+	// Team is the team; its Members include the members of child teams.
+	Team github.Team
+	// Immediate are the members that belong to the team directly.
+	Immediate []string
+}
+
+// BuildTeamChanges is the opposite of BuildNewOwners: it determines the
+// members that need to be added to and removed from each team, so that the
+// team matches the alias with the same name (according to the source).
+// Aliases without a matching team and teams without changes are ignored.
+// Logins are compared case-insensitively. Only immediate members are
+// removed, as members of child teams cannot be removed from the parent; only
+// users who are not members at all are added, so that existing members keep
+// their role.
+func BuildTeamChanges(aliases *prow.OwnersAliases, teams []TeamMembers, source AliasSource) []TeamChange {
+	result := []TeamChange{}
+
+	for _, tm := range teams {
+		team := tm.Team
+
+		members, exists := aliases.Aliases[TeamAliasBase(team, source)]
+		if !exists {
+			continue
+		}
+
+		desired := sets.New[string]()
+		for _, login := range members {
+			desired.Insert(strings.ToLower(login))
+		}
+
+		current := sets.New[string]()
+		for _, login := range team.Members {
+			current.Insert(strings.ToLower(login))
+		}
+
+		change := TeamChange{
+			Team:    team.Slug,
+			Members: len(tm.Immediate),
+			Add:     []string{},
+			Remove:  []string{},
+		}
+
+		for _, login := range tm.Immediate {
+			current.Insert(strings.ToLower(login))
+
+			if !desired.Has(strings.ToLower(login)) {
+				change.Remove = append(change.Remove, login)
+			}
+		}
+
+		change.Add = sets.List(desired.Difference(current))
+		sort.Strings(change.Remove)
+
+		if len(change.Add) > 0 || len(change.Remove) > 0 {
+			result = append(result, change)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Team) < strings.ToLower(result[j].Team)
+	})
+
+	return result
+}
+
+// MassRemovals returns all changes that remove more than maxPercent of a
+// team's members or would leave a team without any members.
+func MassRemovals(changes []TeamChange, maxPercent int) []TeamChange {
+	result := []TeamChange{}
+
+	for _, change := range changes {
+		if change.Empties() || change.RemovedPercentage() > maxPercent {
+			result = append(result, change)
+		}
+	}
+
+	return result
+}
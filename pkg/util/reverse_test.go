@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+)
+
+func TestBuildTeamChanges(t *testing.T) {
+	aliases := &prow.OwnersAliases{
+		Aliases: map[string][]string{
+			"sig-a":   {"alice", "carol"},
+			"sig-b":   {"bob"},
+			"sig-c":   {"dave"},
+			"no-team": {"erin"},
+		},
+	}
+
+	team := func(slug string, members ...string) TeamMembers {
+		return TeamMembers{
+			Team:      github.Team{Slug: slug, Members: members},
+			Immediate: members,
+		}
+	}
+
+	teams := []TeamMembers{
+		team("sig-a", "Alice", "bob"),
+		team("sig-b", "bob"),
+		team("sig-c", "alice", "bob", "carol", "frank"),
+		team("no-alias", "frank"),
+	}
+
+	expected := []TeamChange{
+		{Team: "sig-a", Members: 2, Add: []string{"carol"}, Remove: []string{"bob"}},
+		{Team: "sig-c", Members: 4, Add: []string{"dave"}, Remove: []string{"alice", "bob", "carol", "frank"}},
+	}
+
+	changes := BuildTeamChanges(aliases, teams, AliasSourceSlug)
+	if diff := deep.Equal(changes, expected); diff != nil {
+		t.Fatalf("changes not equal: %v", diff)
+	}
+
+	// sig-a loses 50%, sig-c 100%
+	if diff := deep.Equal(MassRemovals(changes, 50), expected[1:]); diff != nil {
+		t.Errorf("mass removals not equal: %v", diff)
+	}
+
+	if diff := deep.Equal(MassRemovals(changes, 49), expected); diff != nil {
+		t.Errorf("mass removals not equal: %v", diff)
+	}
+}
+
+func TestMassRemovalsEmptyTeam(t *testing.T) {
+	changes := []TeamChange{
+		{Team: "sig-a", Members: 1, Add: []string{}, Remove: []string{"alice"}},
+	}
+
+	if len(MassRemovals(changes, 100)) != 1 {
+		t.Fatal("Emptying a team must always count as a mass removal.")
+	}
+}
+
+func TestBuildTeamChangesChildTeams(t *testing.T) {
+	aliases := &prow.OwnersAliases{
+		Aliases: map[string][]string{
+			"sig-a": {"alice", "carol"},
+		},
+	}
+
+	// bob and carol are only members through a child team
+	teams := []TeamMembers{{
+		Team:      github.Team{Slug: "sig-a", Members: []string{"alice", "bob", "carol", "dave"}},
+		Immediate: []string{"alice", "dave"},
+	}}
+
+	expected := []TeamChange{
+		{Team: "sig-a", Members: 2, Add: []string{}, Remove: []string{"dave"}},
+	}
+
+	if diff := deep.Equal(BuildTeamChanges(aliases, teams, AliasSourceSlug), expected); diff != nil {
+		t.Fatalf("changes not equal: %v", diff)
+	}
+}
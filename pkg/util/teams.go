@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+// AliasSource decides which property of a team the alias name is based on.
+type AliasSource string
+
+const (
+	// AliasSourceName uses the team's display name ("SIG Foo").
+	AliasSourceName AliasSource = "name"
+	// AliasSourceSlug uses the team's slug ("sig-foo").
+	AliasSourceSlug AliasSource = "slug"
+)
+
+var AllAliasSources = []AliasSource{AliasSourceName, AliasSourceSlug}
+
+// TeamAliasBase returns the team's name or slug, depending on the source.
+// Teams without a display name fall back to their slug.
+func TeamAliasBase(team github.Team, source AliasSource) string {
+	if source == AliasSourceSlug || team.Name == "" {
+		return team.Slug
+	}
+
+	return team.Name
+}
+
+// AliasNaming controls how teams from the source organizations are named
+// in the generated aliases files.
+type AliasNaming string
+
+const (
+	// AliasNamingPlain uses the bare team name or slug ("sig-foo").
+	AliasNamingPlain AliasNaming = "plain"
+	// AliasNamingQualified prefixes the name with the org name ("myorg/sig-foo").
+	AliasNamingQualified AliasNaming = "qualified"
+	// AliasNamingPrefixed prefixes the name with the org name and a dash ("myorg-sig-foo").
+	AliasNamingPrefixed AliasNaming = "prefixed"
+)
+
+var AllAliasNamings = []AliasNaming{AliasNamingPlain, AliasNamingQualified, AliasNamingPrefixed}
+
+// ConflictPolicy decides what happens when two source organizations
+// produce the same alias name.
+type ConflictPolicy string
+
+const (
+	// ConflictError aborts with an error.
+	ConflictError ConflictPolicy = "error"
+	// ConflictFirst uses the team from the organization that was given first.
+	ConflictFirst ConflictPolicy = "first"
+	// ConflictMerge combines the members of all teams with the same name.
+	ConflictMerge ConflictPolicy = "merge"
+)
+
+var AllConflictPolicies = []ConflictPolicy{ConflictError, ConflictFirst, ConflictMerge}
+
+type OrgTeams struct {
+	Organization string
+	Teams        []github.Team
+}
+
+func AliasName(org string, name string, naming AliasNaming) string {
+	switch naming {
+	case AliasNamingQualified:
+		return fmt.Sprintf("%s/%s", org, name)
+	case AliasNamingPrefixed:
+		return fmt.Sprintf("%s-%s", org, name)
+	default:
+		return name
+	}
+}
+
+// CombineTeams turns the teams of multiple organizations into a single list
+// of teams, whose slugs are the alias names according to the given source
+// and naming scheme. The order of orgTeams is relevant for the ConflictFirst
+// policy.
+func CombineTeams(orgTeams []OrgTeams, source AliasSource, naming AliasNaming, policy ConflictPolicy) ([]github.Team, error) {
+	combined := map[string]github.Team{}
+	sources := map[string]string{}
+
+	for _, ot := range orgTeams {
+		for _, team := range ot.Teams {
+			name := AliasName(ot.Organization, TeamAliasBase(team, source), naming)
+
+			existing, exists := combined[name]
+			if !exists {
+				team.Slug = name
+				team.Members = append([]string{}, team.Members...)
+				team.Roles = maps.Clone(team.Roles)
+
+				combined[name] = team
+				sources[name] = ot.Organization
+				continue
+			}
+
+			switch policy {
+			case ConflictFirst:
+				// keep the existing team
+
+			case ConflictMerge:
+				combined[name] = mergeTeams(existing, team)
+
+			default:
+				return nil, fmt.Errorf("team %q exists in both %q and %q", name, sources[name], ot.Organization)
+			}
+		}
+	}
+
+	result := []github.Team{}
+	for _, team := range combined {
+		result = append(result, team)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Slug) < strings.ToLower(result[j].Slug)
+	})
+
+	return result, nil
+}
+
+// mergeTeams adds the members of team to existing. Logins are compared
+// case-insensitively; the casing from existing wins.
+func mergeTeams(existing github.Team, team github.Team) github.Team {
+	logins := map[string]string{}
+	for _, m := range existing.Members {
+		logins[strings.ToLower(m)] = m
+	}
+
+	for _, m := range team.Members {
+		if _, exists := logins[strings.ToLower(m)]; !exists {
+			logins[strings.ToLower(m)] = m
+			existing.Members = append(existing.Members, m)
+		}
+	}
+
+	sort.Slice(existing.Members, func(i, j int) bool {
+		return strings.ToLower(existing.Members[i]) < strings.ToLower(existing.Members[j])
+	})
+
+	for login, role := range team.Roles {
+		if existing.Roles == nil {
+			existing.Roles = map[string]github.TeamRole{}
+		}
+
+		if canonical, exists := logins[strings.ToLower(login)]; exists {
+			login = canonical
+		}
+
+		// a maintainer in any team stays a maintainer
+		if existing.Roles[login] != github.TeamRoleMaintainer {
+			existing.Roles[login] = role
+		}
+	}
+
+	return existing
+}
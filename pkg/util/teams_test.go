@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+)
+
+func TestCombineTeams(t *testing.T) {
+	orgTeams := []OrgTeams{
+		{
+			Organization: "main",
+			Teams: []github.Team{
+				{Slug: "sig-a", Members: []string{"1", "2"}},
+				{Slug: "sig-b", Members: []string{"3"}},
+			},
+		},
+		{
+			Organization: "incubator",
+			Teams: []github.Team{
+				{Slug: "sig-a", Members: []string{"2", "4"}},
+			},
+		},
+	}
+
+	testcases := []struct {
+		naming   AliasNaming
+		policy   ConflictPolicy
+		expected []github.Team
+		invalid  bool
+	}{
+		{
+			naming:  AliasNamingPlain,
+			policy:  ConflictError,
+			invalid: true,
+		},
+		{
+			naming: AliasNamingPlain,
+			policy: ConflictFirst,
+			expected: []github.Team{
+				{Slug: "sig-a", Members: []string{"1", "2"}},
+				{Slug: "sig-b", Members: []string{"3"}},
+			},
+		},
+		{
+			naming: AliasNamingPlain,
+			policy: ConflictMerge,
+			expected: []github.Team{
+				{Slug: "sig-a", Members: []string{"1", "2", "4"}},
+				{Slug: "sig-b", Members: []string{"3"}},
+			},
+		},
+		{
+			naming: AliasNamingQualified,
+			policy: ConflictError,
+			expected: []github.Team{
+				{Slug: "incubator/sig-a", Members: []string{"2", "4"}},
+				{Slug: "main/sig-a", Members: []string{"1", "2"}},
+				{Slug: "main/sig-b", Members: []string{"3"}},
+			},
+		},
+		{
+			naming: AliasNamingPrefixed,
+			policy: ConflictError,
+			expected: []github.Team{
+				{Slug: "incubator-sig-a", Members: []string{"2", "4"}},
+				{Slug: "main-sig-a", Members: []string{"1", "2"}},
+				{Slug: "main-sig-b", Members: []string{"3"}},
+			},
+		},
+	}
+
+	for i, testcase := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			result, err := CombineTeams(orgTeams, AliasSourceName, testcase.naming, testcase.policy)
+			if testcase.invalid {
+				if err == nil {
+					t.Fatal("Expected an error, but got none.")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := deep.Equal(result, testcase.expected); diff != nil {
+				t.Fatalf("not equal: %v", diff)
+			}
+		})
+	}
+}
+
+func TestCombineTeamsMergeIgnoresCase(t *testing.T) {
+	orgTeams := []OrgTeams{
+		{
+			Organization: "main",
+			Teams: []github.Team{
+				{Slug: "sig-a", Members: []string{"Alice", "bob"}, Roles: map[string]github.TeamRole{"Alice": github.TeamRoleMember}},
+			},
+		},
+		{
+			Organization: "incubator",
+			Teams: []github.Team{
+				{Slug: "sig-a", Members: []string{"alice", "Carol"}, Roles: map[string]github.TeamRole{"alice": github.TeamRoleMaintainer}},
+			},
+		},
+	}
+
+	result, err := CombineTeams(orgTeams, AliasSourceName, AliasNamingPlain, ConflictMerge)
+	if err != nil {
+		t.Fatalf("Failed to combine teams: %v", err)
+	}
+
+	expected := []github.Team{{
+		Slug:    "sig-a",
+		Members: []string{"Alice", "bob", "Carol"},
+		Roles:   map[string]github.TeamRole{"Alice": github.TeamRoleMaintainer},
+	}}
+
+	if diff := deep.Equal(result, expected); diff != nil {
+		t.Errorf("not equal: %v", diff)
+	}
+}
+
+func TestCombineTeamsAliasSource(t *testing.T) {
+	orgTeams := []OrgTeams{{
+		Organization: "main",
+		Teams: []github.Team{
+			{Slug: "sig-a", Name: "SIG A", Members: []string{"1"}},
+			{Slug: "sig-b", Members: []string{"2"}},
+		},
+	}}
+
+	testcases := []struct {
+		source   AliasSource
+		expected []string
+	}{
+		{
+			source:   AliasSourceName,
+			expected: []string{"main/SIG A", "main/sig-b"},
+		},
+		{
+			source:   AliasSourceSlug,
+			expected: []string{"main/sig-a", "main/sig-b"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(string(testcase.source), func(t *testing.T) {
+			result, err := CombineTeams(orgTeams, testcase.source, AliasNamingQualified, ConflictError)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			names := []string{}
+			for _, team := range result {
+				names = append(names, team.Slug)
+			}
+
+			if diff := deep.Equal(names, testcase.expected); diff != nil {
+				t.Fatalf("not equal: %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+// These types contain only the fields of GitHub's webhook payloads that are
+// relevant to this tool.
+
+type organization struct {
+	Login string `json:"login"`
+}
+
+type team struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	// Parent is only the immediate parent team, not all ancestors.
+	Parent *team `json:"parent"`
+}
+
+type repository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+type membershipEvent struct {
+	Action       string       `json:"action"`
+	Scope        string       `json:"scope"`
+	Team         team         `json:"team"`
+	Organization organization `json:"organization"`
+}
+
+type teamEvent struct {
+	Action       string       `json:"action"`
+	Team         team         `json:"team"`
+	Organization organization `json:"organization"`
+}
+
+type commit struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+type pushEvent struct {
+	Ref        string     `json:"ref"`
+	Deleted    bool       `json:"deleted"`
+	Repository repository `json:"repository"`
+	Commits    []commit   `json:"commits"`
+	HeadCommit *commit    `json:"head_commit"`
+}
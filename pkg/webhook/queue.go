@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+// Runner performs a synchronization for the given scope.
+type Runner func(ctx context.Context, scope syncer.Scope)
+
+// Queue collects scopes and runs them after no new scope has been added for
+// a while, so that bursts of webhooks lead to a single synchronization.
+// Runs never happen concurrently.
+type Queue struct {
+	log     logrus.FieldLogger
+	delay   time.Duration
+	maxWait time.Duration
+	run     Runner
+
+	lock    sync.Mutex
+	pending []syncer.Scope
+	first   time.Time
+	timer   *time.Timer
+	trigger chan struct{}
+}
+
+func NewQueue(log logrus.FieldLogger, delay time.Duration, run Runner) *Queue {
+	return &Queue{
+		log:     log,
+		delay:   delay,
+		maxWait: 5 * delay,
+		run:     run,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Add enqueues a scope and (re)starts the debounce timer.
+func (q *Queue) Add(scope syncer.Scope) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.pending) == 0 {
+		q.first = time.Now()
+	}
+
+	q.pending = append(q.pending, scope)
+
+	// do not postpone the run forever if events keep coming in
+	delay := q.delay
+	if remaining := q.maxWait - time.Since(q.first); remaining < delay {
+		delay = max(remaining, 0)
+	}
+
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+
+	q.timer = time.AfterFunc(delay, func() {
+		select {
+		case q.trigger <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// Start processes the queue until the context is cancelled. A run that is in
+// progress when the context is cancelled is completed first, so runs do not
+// inherit the cancellation; remaining scopes are dropped.
+func (q *Queue) Start(ctx context.Context) {
+	runCtx := context.WithoutCancel(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-q.trigger:
+			for _, scope := range q.take() {
+				if ctx.Err() != nil {
+					return
+				}
+
+				q.run(runCtx, scope)
+			}
+		}
+	}
+}
+
+func (q *Queue) take() []syncer.Scope {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	scopes := MergeScopes(q.pending)
+	q.pending = nil
+
+	return scopes
+}
+
+// MergeScopes deduplicates scopes: a full run supersedes everything else,
+// team scopes are combined into one and repository scopes are deduplicated.
+func MergeScopes(scopes []syncer.Scope) []syncer.Scope {
+	type repoKey struct {
+		org, repo, branch string
+	}
+
+	teams := []string{}
+	repos := []repoKey{}
+
+	for _, scope := range scopes {
+		if scope.IsFull() {
+			return []syncer.Scope{{}}
+		}
+
+		for _, team := range scope.Teams {
+			if !slices.Contains(teams, team) {
+				teams = append(teams, team)
+			}
+		}
+
+		key := repoKey{scope.Organization, scope.Repository, scope.Branch}
+		if scope.Repository != "" && !slices.Contains(repos, key) {
+			repos = append(repos, key)
+		}
+	}
+
+	result := []syncer.Scope{}
+
+	if len(teams) > 0 {
+		slices.Sort(teams)
+		result = append(result, syncer.Scope{Teams: teams})
+	}
+
+	for _, key := range repos {
+		// a scope for all branches of a repository covers the single branches
+		if key.branch != "" && slices.Contains(repos, repoKey{key.org, key.repo, ""}) {
+			continue
+		}
+
+		result = append(result, syncer.Scope{
+			Organization: key.org,
+			Repository:   key.repo,
+			Branch:       key.branch,
+		})
+	}
+
+	return result
+}
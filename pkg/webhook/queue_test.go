@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+func TestMergeScopes(t *testing.T) {
+	testcases := []struct {
+		scopes   []syncer.Scope
+		expected []syncer.Scope
+	}{
+		{
+			scopes: []syncer.Scope{
+				{Teams: []string{"b"}},
+				{Teams: []string{"a", "b"}},
+			},
+			expected: []syncer.Scope{
+				{Teams: []string{"a", "b"}},
+			},
+		},
+		{
+			scopes: []syncer.Scope{
+				{Teams: []string{"a"}},
+				{},
+				{Organization: "org", Repository: "repo"},
+			},
+			expected: []syncer.Scope{{}},
+		},
+		{
+			scopes: []syncer.Scope{
+				{Organization: "org", Repository: "repo", Branch: "main"},
+				{Organization: "org", Repository: "repo", Branch: "main"},
+				{Organization: "org", Repository: "other", Branch: "main"},
+				{Organization: "org", Repository: "other"},
+			},
+			expected: []syncer.Scope{
+				{Organization: "org", Repository: "repo", Branch: "main"},
+				{Organization: "org", Repository: "other"},
+			},
+		},
+	}
+
+	for i, testcase := range testcases {
+		t.Run(fmt.Sprintf("testcase %d", i), func(t *testing.T) {
+			if diff := deep.Equal(MergeScopes(testcase.scopes), testcase.expected); diff != nil {
+				t.Fatalf("not equal: %v", diff)
+			}
+		})
+	}
+}
+
+func TestQueueCompletesRunOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	finished := make(chan error, 1)
+
+	queue := NewQueue(fake.Logger(), 0, func(ctx context.Context, _ syncer.Scope) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished <- ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		queue.Start(ctx)
+		close(stopped)
+	}()
+
+	queue.Add(syncer.Scope{})
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Synchronization did not run.")
+	}
+
+	cancel()
+	<-stopped
+
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Errorf("Expected the run to complete, but its context was cancelled: %v", err)
+		}
+	default:
+		t.Error("Expected Start to wait for the run to complete.")
+	}
+}
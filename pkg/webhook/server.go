@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// Package webhook implements a HTTP server that reacts to GitHub webhooks
+// by synchronizing only the affected aliases files.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+)
+
+// MaxPayloadSize is the maximum size of a webhook payload; GitHub limits
+// payloads to 25 MB.
+const MaxPayloadSize = 25 * 1024 * 1024
+
+// Enqueuer receives the scopes that need to be synchronized.
+type Enqueuer interface {
+	Add(scope syncer.Scope)
+}
+
+type Options struct {
+	// Secret is the webhook secret used to verify the payload signatures.
+	Secret []byte
+	// Organizations are the organizations teams are loaded from.
+	Organizations []string
+	// TargetOrganizations are the organizations whose repositories are updated.
+	TargetOrganizations []string
+	// AliasSource and AliasNaming must match the syncer's naming scheme.
+	AliasSource util.AliasSource
+	AliasNaming util.AliasNaming
+	// AliasesPaths are the paths or glob patterns of the aliases files;
+	// defaults to the OWNERS_ALIASES file in the root directory.
+	AliasesPaths []string
+}
+
+type Server struct {
+	log   logrus.FieldLogger
+	opts  Options
+	queue Enqueuer
+}
+
+var _ http.Handler = &Server{}
+
+func NewServer(log logrus.FieldLogger, opts Options, queue Enqueuer) *Server {
+	return &Server{
+		log:   log,
+		opts:  opts,
+		queue: queue,
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, MaxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if !ValidateSignature(s.opts.Secret, r.Header.Get("X-Hub-Signature-256"), payload) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	eventType := r.Header.Get("X-GitHub-Event")
+	log := s.log.WithFields(logrus.Fields{
+		"event":    eventType,
+		"delivery": r.Header.Get("X-GitHub-Delivery"),
+	})
+
+	scope, relevant, err := s.scopeForEvent(eventType, payload)
+	if err != nil {
+		log.WithError(err).Warn("Invalid webhook payload.")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if relevant {
+		log.WithFields(scopeFields(scope)).Info("Enqueueing synchronization.")
+		s.queue.Add(scope)
+	} else {
+		log.Debug("Ignoring event.")
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// scopeForEvent determines what needs to be synchronized for an event.
+func (s *Server) scopeForEvent(eventType string, payload []byte) (syncer.Scope, bool, error) {
+	switch eventType {
+	case "membership":
+		var event membershipEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return syncer.Scope{}, false, fmt.Errorf("failed to decode payload: %w", err)
+		}
+
+		if event.Scope != "team" || !slices.Contains(s.opts.Organizations, event.Organization.Login) {
+			return syncer.Scope{}, false, nil
+		}
+
+		return s.teamScope(event.Organization.Login, event.Team), true, nil
+
+	case "team":
+		var event teamEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return syncer.Scope{}, false, fmt.Errorf("failed to decode payload: %w", err)
+		}
+
+		if !slices.Contains(s.opts.Organizations, event.Organization.Login) {
+			return syncer.Scope{}, false, nil
+		}
+
+		switch event.Action {
+		case "created":
+			return s.teamScope(event.Organization.Login, event.Team), true, nil
+		case "deleted", "edited":
+			// a deleted or renamed team cannot be found anymore, so the
+			// affected aliases can only be determined by a full run
+			return syncer.Scope{}, true, nil
+		default:
+			return syncer.Scope{}, false, nil
+		}
+
+	case "push":
+		var event pushEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return syncer.Scope{}, false, fmt.Errorf("failed to decode payload: %w", err)
+		}
+
+		branch, isBranch := strings.CutPrefix(event.Ref, "refs/heads/")
+		org := event.Repository.Owner.Login
+
+		if !isBranch || event.Deleted || !slices.Contains(s.opts.TargetOrganizations, org) || !s.touchesAliases(event) {
+			return syncer.Scope{}, false, nil
+		}
+
+		return syncer.Scope{
+			Organization: org,
+			Repository:   event.Repository.Name,
+			Branch:       branch,
+		}, true, nil
+
+	default:
+		return syncer.Scope{}, false, nil
+	}
+}
+
+// teamScope returns the scope for a change to the team. Members of nested
+// teams are also members of all ancestor teams, but the payload only names
+// the immediate parent, so nested teams require a full run.
+func (s *Server) teamScope(org string, t team) syncer.Scope {
+	if t.Parent != nil {
+		return syncer.Scope{}
+	}
+
+	name := util.TeamAliasBase(github.Team{Slug: t.Slug, Name: t.Name}, s.opts.AliasSource)
+
+	return syncer.Scope{
+		Teams: []string{util.AliasName(org, name, s.opts.AliasNaming)},
+	}
+}
+
+// maxPushCommits is the number of commits GitHub includes in push events;
+// larger pushes are cut off.
+const maxPushCommits = 20
+
+func (s *Server) touchesAliases(event pushEvent) bool {
+	// the files of the omitted commits are unknown
+	if len(event.Commits) >= maxPushCommits {
+		return true
+	}
+
+	patterns := s.opts.AliasesPaths
+	if len(patterns) == 0 {
+		patterns = []string{prow.OwnersAliasesFilename}
+	}
+
+	commits := event.Commits
+	if event.HeadCommit != nil {
+		commits = append(commits, *event.HeadCommit)
+	}
+
+	for _, c := range commits {
+		for _, files := range [][]string{c.Added, c.Removed, c.Modified} {
+			if slices.Contains(files, github.SettingsFilename) {
+				return true
+			}
+
+			for _, file := range files {
+				for _, pattern := range patterns {
+					if prow.MatchPath(pattern, file) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func scopeFields(scope syncer.Scope) logrus.Fields {
+	fields := logrus.Fields{}
+
+	if len(scope.Teams) > 0 {
+		fields["teams"] = scope.Teams
+	}
+
+	if scope.Repository != "" {
+		fields["repo"] = fmt.Sprintf("%s/%s", scope.Organization, scope.Repository)
+	}
+
+	if scope.Branch != "" {
+		fields["branch"] = scope.Branch
+	}
+
+	return fields
+}
@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/fake"
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+)
+
+var testSecret = []byte("s3cr3t")
+
+type recordingQueue struct {
+	scopes []syncer.Scope
+}
+
+func (q *recordingQueue) Add(scope syncer.Scope) {
+	q.scopes = append(q.scopes, scope)
+}
+
+func deliver(t *testing.T, url string, event string, payload string, secret []byte) int {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", Sign(secret, []byte(payload)))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to deliver webhook: %v", err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestServerScopes(t *testing.T) {
+	testcases := []struct {
+		name     string
+		event    string
+		payload  string
+		secret   []byte
+		status   int
+		expected []syncer.Scope
+	}{
+		{
+			name:     "invalid signature",
+			event:    "membership",
+			payload:  `{"action":"added","scope":"team","team":{"slug":"sig-a"},"organization":{"login":"main"}}`,
+			secret:   []byte("wrong"),
+			status:   http.StatusForbidden,
+			expected: nil,
+		},
+		{
+			name:     "membership change",
+			event:    "membership",
+			payload:  `{"action":"added","scope":"team","team":{"slug":"sig-a"},"organization":{"login":"main"}}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{Teams: []string{"sig-a"}}},
+		},
+		{
+			name:     "membership change in team with display name",
+			event:    "membership",
+			payload:  `{"action":"added","scope":"team","team":{"slug":"sig-a","name":"SIG A"},"organization":{"login":"main"}}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{Teams: []string{"SIG A"}}},
+		},
+		{
+			name:     "membership change in nested team",
+			event:    "membership",
+			payload:  `{"action":"added","scope":"team","team":{"slug":"sig-a-leads","parent":{"slug":"sig-a"}},"organization":{"login":"main"}}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{}},
+		},
+		{
+			name:     "membership change in unknown org",
+			event:    "membership",
+			payload:  `{"action":"added","scope":"team","team":{"slug":"sig-a"},"organization":{"login":"other"}}`,
+			status:   http.StatusOK,
+			expected: nil,
+		},
+		{
+			name:     "deleted team",
+			event:    "team",
+			payload:  `{"action":"deleted","team":{"slug":"sig-a"},"organization":{"login":"main"}}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{}},
+		},
+		{
+			name:     "push touching aliases",
+			event:    "push",
+			payload:  `{"ref":"refs/heads/release-1.0","repository":{"name":"repo","owner":{"login":"main"}},"commits":[{"modified":["README.md","OWNERS_ALIASES"]}]}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{Organization: "main", Repository: "repo", Branch: "release-1.0"}},
+		},
+		{
+			name:     "push touching aliases in subdirectory",
+			event:    "push",
+			payload:  `{"ref":"refs/heads/main","repository":{"name":"repo","owner":{"login":"main"}},"commits":[{"added":["docs/OWNERS_ALIASES"]}]}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{Organization: "main", Repository: "repo", Branch: "main"}},
+		},
+		{
+			name:     "push touching aliases outside of aliases paths",
+			event:    "push",
+			payload:  `{"ref":"refs/heads/main","repository":{"name":"repo","owner":{"login":"main"}},"commits":[{"added":["vendor/foo/OWNERS_ALIASES"]}]}`,
+			status:   http.StatusOK,
+			expected: nil,
+		},
+		{
+			name:     "push not touching aliases",
+			event:    "push",
+			payload:  `{"ref":"refs/heads/main","repository":{"name":"repo","owner":{"login":"main"}},"commits":[{"modified":["README.md"]}]}`,
+			status:   http.StatusOK,
+			expected: nil,
+		},
+		{
+			name:     "push with truncated commits",
+			event:    "push",
+			payload:  `{"ref":"refs/heads/main","repository":{"name":"repo","owner":{"login":"main"}},"commits":[` + strings.Repeat(`{"modified":["README.md"]},`, 19) + `{"modified":["README.md"]}]}`,
+			status:   http.StatusOK,
+			expected: []syncer.Scope{{Organization: "main", Repository: "repo", Branch: "main"}},
+		},
+		{
+			name:     "tag push",
+			event:    "push",
+			payload:  `{"ref":"refs/tags/v1.0","repository":{"name":"repo","owner":{"login":"main"}},"commits":[{"added":["OWNERS_ALIASES"]}]}`,
+			status:   http.StatusOK,
+			expected: nil,
+		},
+		{
+			name:     "ping",
+			event:    "ping",
+			payload:  `{"zen":"Keep it logically awesome."}`,
+			status:   http.StatusOK,
+			expected: nil,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			queue := &recordingQueue{}
+			server := NewServer(fake.Logger(), Options{
+				Secret:              testSecret,
+				Organizations:       []string{"main"},
+				TargetOrganizations: []string{"main"},
+				AliasSource:         util.AliasSourceName,
+				AliasNaming:         util.AliasNamingPlain,
+				AliasesPaths:        []string{"OWNERS_ALIASES", "docs/**/OWNERS_ALIASES"},
+			}, queue)
+
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			secret := testcase.secret
+			if secret == nil {
+				secret = testSecret
+			}
+
+			status := deliver(t, httpServer.URL, testcase.event, testcase.payload, secret)
+			if status != testcase.status {
+				t.Errorf("Expected status %d, got %d.", testcase.status, status)
+			}
+
+			if diff := deep.Equal(queue.scopes, testcase.expected); diff != nil {
+				t.Errorf("scopes not equal: %v", diff)
+			}
+		})
+	}
+}
+
+func TestMembershipChangeOnlySyncsAffectedTeam(t *testing.T) {
+	const (
+		oldAliases = "aliases:\n  sig-a:\n    - alice\n  sig-b:\n    - carol\n"
+		newAliases = "aliases:\n  sig-a:\n    - alice\n    - bob\n  sig-b:\n    - carol\n"
+	)
+
+	gh := fake.NewGitHub()
+	gh.Teams["main"] = []github.Team{
+		{Slug: "sig-a", Members: []string{"alice", "bob"}},
+		{Slug: "sig-b", Members: []string{"dave"}},
+	}
+	gh.Repositories["main"] = []github.Repository{{
+		ID:   "repo-id",
+		Name: "repo",
+		Branches: []github.Branch{
+			{Name: "main", MostRecentCommit: time.Now(), Aliases: oldAliases},
+		},
+	}}
+
+	gitter := fake.NewGit()
+	gitter.AddRemote("main", "repo", map[string]fake.Files{
+		"main": {prow.OwnersAliasesFilename: oldAliases},
+	})
+
+	s, err := syncer.New(fake.Logger(), syncer.Clients{
+		Teams:        gh,
+		Repositories: gh,
+		PullRequests: gh,
+		Git:          gitter,
+	}, syncer.Options{
+		Organizations:  []string{"main"},
+		Branches:       []string{"main"},
+		MaxAge:         time.Hour,
+		UpdateDirectly: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+
+	var (
+		lock sync.Mutex
+		runs []syncer.Scope
+		done = make(chan struct{})
+	)
+
+	queue := NewQueue(fake.Logger(), 10*time.Millisecond, func(ctx context.Context, scope syncer.Scope) {
+		if _, err := s.RunScoped(ctx, scope); err != nil {
+			t.Errorf("Synchronization failed: %v", err)
+		}
+
+		lock.Lock()
+		runs = append(runs, scope)
+		lock.Unlock()
+
+		close(done)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go queue.Start(ctx)
+
+	server := httptest.NewServer(NewServer(fake.Logger(), Options{
+		Secret:              testSecret,
+		Organizations:       []string{"main"},
+		TargetOrganizations: []string{"main"},
+	}, queue))
+	defer server.Close()
+
+	// a burst of identical events must lead to a single run
+	for i := 0; i < 3; i++ {
+		deliver(t, server.URL, "membership", `{"action":"added","scope":"team","team":{"slug":"sig-a"},"organization":{"login":"main"}}`, testSecret)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Synchronization did not run.")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if diff := deep.Equal(runs, []syncer.Scope{{Teams: []string{"sig-a"}}}); diff != nil {
+		t.Errorf("runs not equal: %v", diff)
+	}
+
+	if len(gitter.Pushes) != 1 {
+		t.Fatalf("Expected 1 push, got %d.", len(gitter.Pushes))
+	}
+
+	// sig-b must not have been touched, even though its team changed as well
+	if content := gitter.Pushes[0].Files[prow.OwnersAliasesFilename]; content != newAliases {
+		t.Errorf("Unexpected aliases file:\n%s", content)
+	}
+}
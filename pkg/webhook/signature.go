@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// ValidateRequest checks the signature of a webhook. GitHub sends the
+// X-Hub-Signature-256 header, but older versions of Prow's hook only send the
+// SHA1-based X-Hub-Signature header to external plugins, which is therefore
+// accepted if allowSHA1 is set.
+func ValidateRequest(secret []byte, header http.Header, payload []byte, allowSHA1 bool) bool {
+	if signature := header.Get("X-Hub-Signature-256"); signature != "" || !allowSHA1 {
+		return ValidateSignature(secret, signature, payload)
+	}
+
+	return validate(secret, header.Get("X-Hub-Signature"), "sha1=", sha1.New, payload)
+}
+
+// ValidateSignature checks the X-Hub-Signature-256 header of a GitHub webhook.
+func ValidateSignature(secret []byte, signature string, payload []byte) bool {
+	return validate(secret, signature, "sha256=", sha256.New, payload)
+}
+
+func validate(secret []byte, signature string, prefix string, hashFunc func() hash.Hash, payload []byte) bool {
+	hexSignature, found := strings.CutPrefix(signature, prefix)
+	if !found {
+		return false
+	}
+
+	expected, err := hex.DecodeString(hexSignature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(hashFunc, secret)
+	mac.Write(payload)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// Sign returns the X-Hub-Signature-256 header value for the payload.
+func Sign(secret []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
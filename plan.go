@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/metrics"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+// runPlan determines all changes and writes them into a plan file, which
+// can be reviewed and then executed using runApply.
+func runPlan(args []string) {
+	opt := defaultOptions()
+
+	output := "aliases-plan.yaml"
+
+	fs := pflag.NewFlagSet(programName+" plan", pflag.ExitOnError)
+	opt.addFlags(fs)
+	fs.StringVar(&output, "output", output, "File to write the plan to")
+	fs.Parse(args)
+
+	if opt.version {
+		printVersion()
+		return
+	}
+
+	log := newLogger(opt.verbose)
+	opt.complete(log)
+
+	logger := opt.fieldLogger(log)
+
+	ctx := context.Background()
+	client := opt.newClient(ctx, logger)
+
+	plan, result, err := opt.newSyncer(logger, client, metrics.New(client)).Plan(ctx)
+
+	logger = withRunSummary(logger, client, result)
+
+	if err != nil {
+		logLimitViolations(logger, err)
+		logger.Fatalf("Failure: %v", err)
+	}
+
+	for _, change := range plan.Changes {
+		fmt.Printf("# %s/%s@%s (%s)\n%s\n", change.Organization, change.Repository, change.Branch, change.BaseOID, change.Diff)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		logger.Fatalf("Failed to create plan file: %v", err)
+	}
+	defer f.Close()
+
+	if err := plan.Encode(f); err != nil {
+		logger.Fatalf("Failed to write plan: %v", err)
+	}
+
+	logger.WithField("changes", len(plan.Changes)).WithField("plan", output).Info("Plan created.")
+}
+
+// runApply executes a plan created by runPlan.
+func runApply(args []string) {
+	opt := defaultOptions()
+
+	fs := pflag.NewFlagSet(programName+" apply", pflag.ExitOnError)
+	opt.addClientFlags(fs)
+	opt.addWriteFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s apply [flags] PLAN_FILE\n", programName)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if opt.version {
+		printVersion()
+		return
+	}
+
+	log := newLogger(opt.verbose)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	plan, err := syncer.LoadPlan(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load plan: %v", err)
+	}
+
+	if len(plan.Changes) == 0 {
+		log.Info("Plan contains no changes.")
+		return
+	}
+
+	// the plan already contains the final aliases files, so these options
+	// are only required to satisfy the syncer
+	if len(opt.organizations) == 0 {
+		opt.organizations = plan.Organizations()
+	}
+	opt.branches = []string{"*"}
+
+	opt.complete(log)
+
+	logger := opt.fieldLogger(log)
+
+	ctx := context.Background()
+	client := opt.newClient(ctx, logger)
+
+	result, err := opt.newSyncer(logger, client, metrics.New(client)).Apply(ctx, plan)
+
+	logger = withRunSummary(logger, client, result)
+
+	if err != nil {
+		logger.Fatalf("Failure: %v", err)
+	}
+
+	if result.Failed() {
+		logger.Fatal("Not all changes could be applied.")
+	}
+
+	logger.Info("Plan applied.")
+}
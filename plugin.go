@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/pflag"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/metrics"
+	"go.xrstf.de/prow-aliases-syncer/pkg/plugin"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+)
+
+// runPlugin starts a Prow external plugin that synchronizes single
+// repositories when requested via the /sync-aliases command.
+func runPlugin(args []string) {
+	opt := defaultOptions()
+
+	listenAddr := ":8888"
+	secretFile := ""
+	trustedUsers := []string{}
+	allowSHA1 := false
+
+	fs := pflag.NewFlagSet(programName+" plugin", pflag.ExitOnError)
+	opt.addFlags(fs)
+	fs.StringVar(&listenAddr, "listen", listenAddr, "Address to listen on for events from Prow's hook")
+	fs.StringVar(&secretFile, "hmac-secret-file", secretFile, "File with the HMAC secret shared with Prow's hook")
+	fs.StringSliceVar(&trustedUsers, "trusted-user", trustedUsers, "User that may use the command even if they are not a member of the repository's organization (can be given multiple times)")
+	fs.BoolVar(&allowSHA1, "allow-sha1-signature", allowSHA1, "Accept the SHA1-based X-Hub-Signature header sent by older versions of Prow's hook")
+	fs.Parse(args)
+
+	if opt.version {
+		printVersion()
+		return
+	}
+
+	log := newLogger(opt.verbose)
+	opt.complete(log)
+
+	secret := readSecret(log, secretFile)
+	logger := opt.fieldLogger(log)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	client := opt.newClient(context.Background(), logger)
+	m := metrics.New(client)
+
+	server := plugin.NewServer(logger, plugin.Options{
+		Secret:              secret,
+		TargetOrganizations: opt.targetOrganizations,
+		TrustedUsers:        trustedUsers,
+		AllowSHA1:           allowSHA1,
+	}, plugin.Clients{
+		PullRequests: client,
+		Comments:     client,
+		Members:      client,
+	}, &observedRunner{
+		syncer:  opt.newSyncer(logger, client, m),
+		metrics: m,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	mux.Handle("/", server)
+
+	logger.WithField("addr", listenAddr).Info("Listening for events…")
+
+	if err := listenAndServe(ctx, listenAddr, mux); err != nil {
+		logger.Fatalf("Failed to serve: %v", err)
+	}
+
+	// wait for in-progress synchronizations to finish
+	server.Wait()
+
+	logger.Info("Shutdown completed.")
+}
+
+// observedRunner records the outcome of every synchronization requested
+// via the plugin.
+type observedRunner struct {
+	syncer  *syncer.Syncer
+	metrics *metrics.Metrics
+}
+
+func (r *observedRunner) RunScoped(ctx context.Context, scope syncer.Scope) (*syncer.Result, error) {
+	result, err := r.syncer.RunScoped(ctx, scope)
+	r.metrics.ObserveRun(result, err)
+
+	return result, err
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/metrics"
+	"go.xrstf.de/prow-aliases-syncer/pkg/syncer"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+	"go.xrstf.de/prow-aliases-syncer/pkg/webhook"
+)
+
+// runServe starts a HTTP server that receives GitHub webhooks and
+// synchronizes the affected aliases.
+func runServe(args []string) {
+	opt := defaultOptions()
+
+	listenAddr := ":8080"
+	secretFile := ""
+	debounce := 30 * time.Second
+
+	fs := pflag.NewFlagSet(programName+" serve", pflag.ExitOnError)
+	opt.addFlags(fs)
+	fs.StringVar(&listenAddr, "listen", listenAddr, "Address to listen on for webhooks")
+	fs.StringVar(&secretFile, "hmac-secret-file", secretFile, "File with the secret used to verify webhook signatures")
+	fs.DurationVar(&debounce, "debounce", debounce, "Wait this long for more events before synchronizing")
+	fs.Parse(args)
+
+	if opt.version {
+		printVersion()
+		return
+	}
+
+	log := newLogger(opt.verbose)
+	opt.complete(log)
+
+	secret := readSecret(log, secretFile)
+
+	logger := opt.fieldLogger(log)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	client := opt.newClient(context.Background(), logger)
+	m := metrics.New(client)
+	s := opt.newSyncer(logger, client, m)
+
+	queue := webhook.NewQueue(logger, debounce, func(ctx context.Context, scope syncer.Scope) {
+		result, err := s.RunScoped(ctx, scope)
+		m.ObserveRun(result, err)
+		opt.saveState(logger)
+
+		slog := withRunSummary(logger, client, result)
+		if err != nil {
+			logLimitViolations(slog, err)
+			slog.WithError(err).Error("Synchronization failed.")
+			return
+		}
+
+		slog.Info("Synchronization completed.")
+	})
+
+	server := webhook.NewServer(logger, webhook.Options{
+		Secret:              secret,
+		Organizations:       opt.organizations,
+		TargetOrganizations: opt.targetOrganizations,
+		AliasSource:         util.AliasSource(opt.aliasSource),
+		AliasNaming:         util.AliasNaming(opt.aliasNaming),
+		AliasesPaths:        opt.aliasesPaths,
+	}, queue)
+
+	mux := http.NewServeMux()
+	mux.Handle("/hook", server)
+	mux.Handle("/metrics", m.Handler())
+
+	queueDone := make(chan struct{})
+	go func() {
+		queue.Start(ctx)
+		close(queueDone)
+	}()
+
+	logger.WithField("addr", listenAddr).Info("Listening for webhooks…")
+
+	if err := listenAndServe(ctx, listenAddr, mux); err != nil {
+		logger.Fatalf("Failed to serve: %v", err)
+	}
+
+	// wait for an in-progress synchronization to finish
+	<-queueDone
+
+	logger.Info("Shutdown completed.")
+}
+
+// listenAndServe runs a HTTP server until the context is cancelled.
+func listenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// readSecret reads the HMAC secret and terminates the program on error.
+func readSecret(log logrus.FieldLogger, secretFile string) []byte {
+	if secretFile == "" {
+		log.Fatal("No --hmac-secret-file given.")
+	}
+
+	secret, err := os.ReadFile(secretFile)
+	if err != nil {
+		log.Fatalf("Failed to read --hmac-secret-file: %v", err)
+	}
+
+	return []byte(strings.TrimSpace(string(secret)))
+}
@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+
+	"go.xrstf.de/prow-aliases-syncer/pkg/github"
+	"go.xrstf.de/prow-aliases-syncer/pkg/prow"
+	"go.xrstf.de/prow-aliases-syncer/pkg/teams"
+	"go.xrstf.de/prow-aliases-syncer/pkg/util"
+)
+
+// runTeams dispatches the "teams" subcommands.
+func runTeams(args []string) {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintf(os.Stderr, "Usage: %s teams export [flags]\n", programName)
+		os.Exit(2)
+	}
+
+	runTeamsExport(args[1:])
+}
+
+// runTeamsExport writes a snapshot of the teams in all --org organizations
+// to stdout.
+func runTeamsExport(args []string) {
+	opt := defaultOptions()
+
+	fs := pflag.NewFlagSet(programName+" teams export", pflag.ExitOnError)
+	opt.addClientFlags(fs)
+	fs.Parse(args)
+
+	if opt.version {
+		printVersion()
+		return
+	}
+
+	log := newLogger(opt.verbose)
+	opt.completeClient(log)
+
+	logger := opt.fieldLogger(log)
+	client := opt.newClient(context.Background(), logger)
+
+	snapshot := teams.NewSnapshot()
+
+	for _, org := range opt.organizations {
+		olog := logger.WithField("source", org)
+		olog.Info("Listing teams…")
+
+		orgTeams, err := client.GetTeams(org)
+		if err != nil {
+			olog.Fatalf("Failed to list teams: %v", err)
+		}
+
+		olog.Infof("Found %d teams.", len(orgTeams))
+
+		snapshot.Add(org, orgTeams)
+	}
+
+	if err := snapshot.Encode(os.Stdout); err != nil {
+		logger.Fatalf("Failed to write snapshot: %v", err)
+	}
+}
+
+// runTeamsFromAliases updates the GitHub teams to match the aliases file in
+// a repository, i.e. the opposite of the regular synchronization. Without
+// --apply, the changes are only printed.
+func runTeamsFromAliases(args []string) {
+	opt := defaultOptions()
+
+	var (
+		repo             string
+		branch           = "main"
+		aliasesPath      = prow.OwnersAliasesFilename
+		maxRemoval       = 25
+		allowMassRemoval bool
+		apply            bool
+		confirm          string
+	)
+
+	fs := pflag.NewFlagSet(programName+" teams-from-aliases", pflag.ExitOnError)
+	opt.addClientFlags(fs)
+	fs.StringVar(&repo, "repo", repo, "Repository in the --org that contains the aliases file")
+	fs.StringVar(&branch, "branch", branch, "Branch to read the aliases file from")
+	fs.StringVar(&aliasesPath, "aliases-path", aliasesPath, "Path of the aliases file")
+	opt.addAliasSourceFlag(fs)
+	fs.IntVar(&maxRemoval, "max-removal-percent", maxRemoval, "Refuse to remove more than this percentage of a team's members")
+	fs.BoolVar(&allowMassRemoval, "allow-mass-removal", allowMassRemoval, "Apply changes even if they exceed --max-removal-percent or empty a team")
+	fs.BoolVar(&apply, "apply", apply, "Change the team memberships on GitHub (requires --confirm)")
+	fs.StringVar(&confirm, "confirm", confirm, "Name of the organization whose teams are changed, as a confirmation for --apply")
+	fs.Parse(args)
+
+	if opt.version {
+		printVersion()
+		return
+	}
+
+	log := newLogger(opt.verbose)
+	opt.completeClient(log)
+	opt.completeAliasSource(log)
+
+	if len(opt.organizations) != 1 {
+		log.Fatal("Exactly one --org must be given.")
+	}
+
+	if repo == "" {
+		log.Fatal("No --repo given.")
+	}
+
+	if apply && confirm != opt.organizations[0] {
+		log.Fatalf("--apply requires --confirm=%s.", opt.organizations[0])
+	}
+
+	org := opt.organizations[0]
+	logger := opt.fieldLogger(log).WithField("org", org)
+	client := opt.newClient(context.Background(), logger)
+
+	content, err := readAliasesFile(client, org, repo, branch, aliasesPath)
+	if err != nil {
+		logger.Fatalf("Failed to read aliases file: %v", err)
+	}
+
+	aliases, err := prow.FromString(content)
+	if err != nil {
+		logger.Fatalf("Invalid aliases file: %v", err)
+	}
+
+	if len(aliases.Aliases) == 0 {
+		logger.Fatal("Aliases file contains no aliases, refusing to continue.")
+	}
+
+	logger.Info("Listing teams…")
+
+	orgTeams, err := client.GetTeams(org)
+	if err != nil {
+		logger.Fatalf("Failed to list teams: %v", err)
+	}
+
+	source := util.AliasSource(opt.aliasSource)
+	teamMembers := []util.TeamMembers{}
+
+	for _, team := range orgTeams {
+		if _, exists := aliases.Aliases[util.TeamAliasBase(team, source)]; !exists {
+			continue
+		}
+
+		// GetTeams only returns the first 100 members of each team
+		all, err := client.GetTeamMembers(org, team.Slug, false)
+		if err != nil {
+			logger.Fatalf("Failed to list members of team %s: %v", team.Slug, err)
+		}
+
+		immediate, err := client.GetTeamMembers(org, team.Slug, true)
+		if err != nil {
+			logger.Fatalf("Failed to list immediate members of team %s: %v", team.Slug, err)
+		}
+
+		team.Members = all
+		teamMembers = append(teamMembers, util.TeamMembers{
+			Team:      team,
+			Immediate: immediate,
+		})
+	}
+
+	changes := util.BuildTeamChanges(aliases, teamMembers, source)
+
+	for _, change := range changes {
+		fmt.Printf("# %s/%s (%d members)\n", org, change.Team, change.Members)
+
+		for _, login := range change.Add {
+			fmt.Printf("+ %s\n", login)
+		}
+
+		for _, login := range change.Remove {
+			fmt.Printf("- %s\n", login)
+		}
+
+		fmt.Println()
+	}
+
+	logger.WithField("teams", len(changes)).Info("Changes determined.")
+
+	if massRemovals := util.MassRemovals(changes, maxRemoval); len(massRemovals) > 0 && !allowMassRemoval {
+		for _, change := range massRemovals {
+			logger.WithFields(logrus.Fields{
+				"team":    change.Team,
+				"members": change.Members,
+				"removed": len(change.Remove),
+			}).Error("Too many members would be removed.")
+		}
+
+		logger.Fatalf("Refusing to remove more than %d%% of the members of a team or to empty a team; use --allow-mass-removal if this is intended.", maxRemoval)
+	}
+
+	if !apply {
+		logger.Infof("Not applying changes; use --apply --confirm=%s to change the teams.", org)
+		return
+	}
+
+	failed := 0
+
+	for _, change := range changes {
+		tlog := logger.WithField("team", change.Team)
+		failedBefore := failed
+
+		for _, login := range change.Add {
+			if err := client.AddTeamMember(org, change.Team, login); err != nil {
+				tlog.WithError(err).WithField("login", login).Warn("Failed to add member.")
+				failed++
+			}
+		}
+
+		for _, login := range change.Remove {
+			if err := client.RemoveTeamMember(org, change.Team, login); err != nil {
+				tlog.WithError(err).WithField("login", login).Warn("Failed to remove member.")
+				failed++
+			}
+		}
+
+		if failed == failedBefore {
+			tlog.Info("Team updated.")
+		}
+	}
+
+	if failed > 0 {
+		logger.Fatalf("Failed to apply %d changes.", failed)
+	}
+}
+
+// readAliasesFile returns the content of an aliases file in a branch.
+func readAliasesFile(client *github.Client, org, repo, branch, path string) (string, error) {
+	repository, err := client.GetRepository(org, repo, github.ActivityOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	if repository == nil {
+		return "", fmt.Errorf("repository %s/%s does not exist", org, repo)
+	}
+
+	for _, b := range repository.Branches {
+		if b.Name != branch {
+			continue
+		}
+
+		content := b.Aliases
+		if path != prow.OwnersAliasesFilename {
+			files, err := client.GetAliasesFiles(org, repo, b.HeadOID, []string{path})
+			if err != nil {
+				return "", err
+			}
+
+			content = files[path]
+		}
+
+		if content == "" {
+			return "", fmt.Errorf("branch %q has no %s file", branch, path)
+		}
+
+		return content, nil
+	}
+
+	return "", fmt.Errorf("branch %q does not exist", branch)
+}